@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Lockenrocky/chirpy/internal/auth"
+	"github.com/Lockenrocky/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	accountStatusActive    = "active"
+	accountStatusSuspended = "suspended"
+	accountStatusBanned    = "banned"
+)
+
+// authenticate returns the user the request's access token belongs to. If
+// the token is missing or invalid it writes a 401 and returns false; if the
+// account is banned or suspended, a 403.
+func (cfg *apiConfig) authenticate(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, errCodeMissingToken, "Could not find access token")
+		return uuid.Nil, false
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtKeys)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, errCodeInvalidToken, "Invalid access token")
+		return uuid.Nil, false
+	}
+	if !cfg.checkAccount(w, r, userID) {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// optionalUser returns the caller's user ID for endpoints that work without
+// authentication but can personalise their response. A missing or invalid
+// token, or a locked account, is treated as an anonymous caller.
+func (cfg *apiConfig) optionalUser(r *http.Request) (uuid.UUID, bool) {
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtKeys)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	if _, restricted, err := cfg.accountRestriction(r.Context(), userID); err != nil || restricted {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// checkAccount is the per-request half of account status enforcement: access
+// tokens stay valid until they expire, so a ban or suspension has to be
+// looked up every time. It writes a 403 and returns false if the account is
+// locked.
+func (cfg *apiConfig) checkAccount(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	restriction, restricted, err := cfg.accountRestriction(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error checking status of user %s: %s", userID, err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not check account status")
+		return false
+	}
+	if restricted {
+		respondAccountRestricted(w, restriction.Status, restriction.SuspendedUntil)
+		return false
+	}
+	return true
+}
+
+// accountRestriction looks up whether userID is currently banned or
+// suspended. A user that does not exist is not restricted.
+func (cfg *apiConfig) accountRestriction(ctx context.Context, userID uuid.UUID) (database.SelectAccountRestrictionRow, bool, error) {
+	restriction, err := cfg.db.SelectAccountRestriction(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return restriction, false, nil
+	}
+	if err != nil {
+		return restriction, false, err
+	}
+	return restriction, true, nil
+}
+
+// accountRestricted reports whether a user with this status may not log in
+// or use the API. A suspension lapses by itself once suspendedUntil passes.
+func accountRestricted(status string, suspendedUntil sql.NullTime) bool {
+	switch status {
+	case accountStatusBanned:
+		return true
+	case accountStatusSuspended:
+		return suspendedUntil.Valid && suspendedUntil.Time.After(time.Now())
+	}
+	return false
+}
+
+func respondAccountRestricted(w http.ResponseWriter, status string, suspendedUntil sql.NullTime) {
+	if status == accountStatusBanned {
+		respondWithError(w, http.StatusForbidden, errCodeAccountBanned, "This account has been banned")
+		return
+	}
+	respondWithError(w, http.StatusForbidden, errCodeAccountSuspended, "This account is suspended until "+suspendedUntil.Time.UTC().Format(time.RFC3339))
+}
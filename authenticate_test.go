@@ -0,0 +1,44 @@
+package main
+
+import (
+	"database/sql"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAccountRestricted(t *testing.T) {
+	future := sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true}
+	past := sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true}
+
+	tests := []struct {
+		name           string
+		status         string
+		suspendedUntil sql.NullTime
+		want           bool
+	}{
+		{"active", accountStatusActive, sql.NullTime{}, false},
+		{"suspended", accountStatusSuspended, future, true},
+		{"suspension lapsed", accountStatusSuspended, past, false},
+		{"banned", accountStatusBanned, sql.NullTime{}, true},
+		{"banned after suspension", accountStatusBanned, past, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := accountRestricted(tt.status, tt.suspendedUntil); got != tt.want {
+				t.Errorf("accountRestricted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRespondAccountRestricted(t *testing.T) {
+	rec := httptest.NewRecorder()
+	respondAccountRestricted(rec, accountStatusBanned, sql.NullTime{})
+	assertErrorEnvelope(t, rec, 403, errCodeAccountBanned)
+
+	rec = httptest.NewRecorder()
+	respondAccountRestricted(rec, accountStatusSuspended, sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true})
+	assertErrorEnvelope(t, rec, 403, errCodeAccountSuspended)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"database/sql"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// gooseMarker matches the lines that split a migration into its sections.
+var gooseMarker = regexp.MustCompile(`(?im)^-- \+goose (up|down)\s*$`)
+
+// openTestDB returns a connection to a fresh schema, with every migration in
+// sql/schema applied, in the Postgres database at CHIRPY_TEST_DB_URL. The
+// schema is dropped when the test ends. Tests that need it are skipped if
+// the variable is not set.
+func openTestDB(tb testing.TB) *sql.DB {
+	tb.Helper()
+	dbURL := os.Getenv("CHIRPY_TEST_DB_URL")
+	if dbURL == "" {
+		tb.Skip("CHIRPY_TEST_DB_URL is not set")
+	}
+
+	admin, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		tb.Fatalf("sql.Open() error = %v", err)
+	}
+	tb.Cleanup(func() { admin.Close() })
+
+	schema := "chirpy_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		tb.Fatalf("creating schema: %v", err)
+	}
+	tb.Cleanup(func() {
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			tb.Errorf("dropping schema %s: %v", schema, err)
+		}
+	})
+
+	conn, err := sql.Open("postgres", withSearchPath(tb, dbURL, schema))
+	if err != nil {
+		tb.Fatalf("sql.Open() error = %v", err)
+	}
+	tb.Cleanup(func() { conn.Close() })
+
+	paths, err := filepath.Glob(filepath.Join("sql", "schema", "*.sql"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	for _, path := range paths {
+		dat, err := os.ReadFile(path)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		if _, err := conn.Exec(migrationUp(string(dat))); err != nil {
+			tb.Fatalf("applying %s: %v", filepath.Base(path), err)
+		}
+	}
+	return conn
+}
+
+// migrationUp returns the Up section of a goose migration.
+func migrationUp(migration string) string {
+	sections := gooseMarker.FindAllStringSubmatchIndex(migration, -1)
+	for i, m := range sections {
+		if !strings.EqualFold(migration[m[2]:m[3]], "up") {
+			continue
+		}
+		end := len(migration)
+		if i+1 < len(sections) {
+			end = sections[i+1][0]
+		}
+		return migration[m[1]:end]
+	}
+	return ""
+}
+
+// withSearchPath adds search_path to a connection string, in either URL or
+// key=value form, so every connection in the pool uses schema.
+func withSearchPath(tb testing.TB, dbURL, schema string) string {
+	tb.Helper()
+	if !strings.HasPrefix(dbURL, "postgres://") && !strings.HasPrefix(dbURL, "postgresql://") {
+		return dbURL + " search_path=" + schema
+	}
+	u, err := url.Parse(dbURL)
+	if err != nil {
+		tb.Fatalf("parsing CHIRPY_TEST_DB_URL: %v", err)
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
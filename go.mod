@@ -10,3 +10,11 @@ require (
 )
 
 require github.com/golang-jwt/jwt/v5 v5.2.2
+
+require golang.org/x/text v0.24.0
+
+require gopkg.in/yaml.v3 v3.0.1
+
+require github.com/coder/websocket v1.8.15
+
+require golang.org/x/image v0.26.0
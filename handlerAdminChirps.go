@@ -0,0 +1,122 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Lockenrocky/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// adminChirpResp is a chirp as moderators see it, deleted or not.
+type adminChirpResp struct {
+	resp
+	Deleted_at *time.Time `json:"deleted_at"`
+	Deleted_by *uuid.UUID `json:"deleted_by"`
+}
+
+func chirpToAdminResp(chirp database.Chirp) adminChirpResp {
+	out := adminChirpResp{resp: chirpToResp(chirp)}
+	if chirp.DeletedAt.Valid {
+		out.Deleted_at = &chirp.DeletedAt.Time
+	}
+	if chirp.DeletedBy.Valid {
+		out.Deleted_by = &chirp.DeletedBy.UUID
+	}
+	return out
+}
+
+type adminChirpPage struct {
+	Chirps     []adminChirpResp `json:"chirps"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// handlerGetDeletedChirps lists deleted chirps that have not been purged yet,
+// most recently deleted first.
+func (cfg *apiConfig) handlerGetDeletedChirps(w http.ResponseWriter, r *http.Request) {
+	page, ok := parsePageParams(w, r)
+	if !ok {
+		return
+	}
+
+	chirps, err := cfg.db.SelectDeletedChirpsPage(r.Context(), database.SelectDeletedChirpsPageParams{
+		BeforeDeletedAt: page.cursorTime,
+		BeforeID:        page.cursorID,
+		PageSize:        int32(page.limit + 1),
+	})
+	if err != nil {
+		log.Printf("Error selecting deleted chirps: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get chirps")
+		return
+	}
+
+	result := adminChirpPage{Chirps: []adminChirpResp{}}
+	if len(chirps) > page.limit {
+		chirps = chirps[:page.limit]
+		last := chirps[len(chirps)-1]
+		result.NextCursor = encodeCursor(chirpCursor{CreatedAt: last.DeletedAt.Time, ID: last.ID})
+	}
+	for _, chirp := range chirps {
+		result.Chirps = append(result.Chirps, chirpToAdminResp(chirp))
+	}
+
+	annotated := make([]*resp, len(result.Chirps))
+	for i := range result.Chirps {
+		annotated[i] = &result.Chirps[i].resp
+	}
+	if err := cfg.annotateChirps(r, annotated); err != nil {
+		log.Printf("Error annotating chirps: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get chirps")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// handlerAdminGetChirp returns a chirp whether or not it has been deleted.
+func (cfg *apiConfig) handlerAdminGetChirp(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Chirp not found")
+		return
+	}
+
+	chirp, err := cfg.db.SelectChirpIncludingDeleted(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Chirp not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error selecting chirp %s: %s", id, err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get chirp")
+		return
+	}
+
+	result := chirpToAdminResp(chirp)
+	if err := cfg.annotateChirps(r, []*resp{&result.resp}); err != nil {
+		log.Printf("Error annotating chirps: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get chirp")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// handlerPurgeDeletedChirps runs the purge job now instead of waiting for its
+// next scheduled run.
+func (cfg *apiConfig) handlerPurgeDeletedChirps(w http.ResponseWriter, r *http.Request) {
+	n, err := cfg.purgeDeletedChirps(r.Context())
+	if err != nil {
+		log.Printf("Error purging deleted chirps: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not purge chirps")
+		return
+	}
+
+	type resp struct {
+		Purged int `json:"purged"`
+	}
+	respondWithJSON(w, http.StatusOK, resp{Purged: n})
+}
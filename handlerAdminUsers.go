@@ -0,0 +1,135 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Lockenrocky/chirpy/internal/auth"
+	"github.com/Lockenrocky/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// handlerSetUserRole answers PUT /admin/users/{userID}/role. Admins cannot
+// change their own role, so the last admin cannot lock everyone out. The new
+// role applies to the user's next access token.
+func (cfg *apiConfig) handlerSetUserRole(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "User not found")
+		return
+	}
+
+	type parameters struct {
+		Role string `json:"role"`
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "Could not decode parameters")
+		return
+	}
+	role, err := auth.ParseRole(params.Role)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "role must be user, moderator or admin")
+		return
+	}
+
+	if claims, ok := accessClaimsFrom(r.Context()); ok && claims.UserID == userID {
+		respondWithError(w, http.StatusForbidden, errCodeForbidden, "You cannot change your own role")
+		return
+	}
+
+	user, err := cfg.db.SetUserRole(r.Context(), database.SetUserRoleParams{ID: userID, Role: string(role)})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error setting role of user %s: %s", userID, err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not set role")
+		return
+	}
+
+	type resp struct {
+		ID    uuid.UUID `json:"id"`
+		Email string    `json:"email"`
+		Role  string    `json:"role"`
+	}
+	respondWithJSON(w, http.StatusOK, resp{ID: user.ID, Email: user.Email, Role: user.Role})
+}
+
+// handlerSetUserStatus answers PUT /admin/users/{userID}/status with a body
+// like {"status": "suspended", "duration": "72h"}. Suspending or banning a
+// user revokes their refresh tokens; their current access token is rejected
+// from the next request on.
+func (cfg *apiConfig) handlerSetUserStatus(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "User not found")
+		return
+	}
+
+	type parameters struct {
+		Status   string `json:"status"`
+		Duration string `json:"duration"`
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "Could not decode parameters")
+		return
+	}
+
+	var suspendedUntil sql.NullTime
+	switch params.Status {
+	case accountStatusSuspended:
+		d, err := time.ParseDuration(params.Duration)
+		if err != nil || d <= 0 || d > maxSuspension {
+			respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "duration must be a positive duration of at most "+maxSuspension.String())
+			return
+		}
+		suspendedUntil = sql.NullTime{Time: time.Now().UTC().Add(d), Valid: true}
+	case accountStatusActive, accountStatusBanned:
+		if params.Duration != "" {
+			respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "duration only applies to suspensions")
+			return
+		}
+	default:
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "status must be active, suspended or banned")
+		return
+	}
+
+	if claims, ok := accessClaimsFrom(r.Context()); ok && claims.UserID == userID {
+		respondWithError(w, http.StatusForbidden, errCodeForbidden, "You cannot change your own status")
+		return
+	}
+
+	user, err := cfg.db.SetUserStatus(r.Context(), database.SetUserStatusParams{
+		ID:             userID,
+		Status:         params.Status,
+		SuspendedUntil: suspendedUntil,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error setting status of user %s: %s", userID, err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not set status")
+		return
+	}
+
+	type resp struct {
+		ID              uuid.UUID  `json:"id"`
+		Email           string     `json:"email"`
+		Status          string     `json:"status"`
+		Suspended_until *time.Time `json:"suspended_until"`
+	}
+	out := resp{ID: user.ID, Email: user.Email, Status: user.Status}
+	if user.SuspendedUntil.Valid {
+		out.Suspended_until = &user.SuspendedUntil.Time
+	}
+	respondWithJSON(w, http.StatusOK, out)
+}
@@ -1,82 +1,162 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"log"
 	"net/http"
-	"sort"
 	"time"
 
-	"github.com/Lockenrocky/chirpy/internal/auth"
 	"github.com/Lockenrocky/chirpy/internal/database"
 	"github.com/google/uuid"
 )
 
 type resp struct {
-	ID         uuid.UUID `json:"id"`
-	Created_at time.Time `json:"created_at"`
-	Updated_at time.Time `json:"updated_at"`
-	Body       string    `json:"body"`
-	User_id    uuid.UUID `json:"user_id"`
+	ID          uuid.UUID   `json:"id"`
+	Created_at  time.Time   `json:"created_at"`
+	Updated_at  time.Time   `json:"updated_at"`
+	Body        string      `json:"body"`
+	User_id     uuid.UUID   `json:"user_id"`
+	In_reply_to *uuid.UUID  `json:"in_reply_to"`
+	Like_count  int32       `json:"like_count"`
+	Liked_by_me bool        `json:"liked_by_me"`
+	Entities    []entity    `json:"entities"`
+	Media       []mediaResp `json:"media"`
+}
+
+func chirpToResp(chirp database.Chirp) resp {
+	r := resp{
+		ID:         chirp.ID,
+		Created_at: chirp.CreatedAt,
+		Updated_at: chirp.UpdatedAt,
+		Body:       chirp.Body,
+		User_id:    chirp.UserID,
+		Like_count: chirp.LikeCount,
+		Entities:   bodyEntities(chirp.Body),
+		Media:      []mediaResp{},
+	}
+	if chirp.InReplyTo.Valid {
+		parent := chirp.InReplyTo.UUID
+		r.In_reply_to = &parent
+	}
+	return r
+}
+
+// annotateChirps fills in the parts of chirps that depend on other tables or
+// on who is asking: liked_by_me, the users behind mentions, and attachments.
+func (cfg *apiConfig) annotateChirps(r *http.Request, chirps []*resp) error {
+	if err := cfg.markLikedByMe(r, chirps); err != nil {
+		return err
+	}
+	if err := cfg.resolveMentions(r, chirps); err != nil {
+		return err
+	}
+	return cfg.attachMediaResp(r, chirps)
 }
 
 func (cfg *apiConfig) handlerCreateChirp(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+
 	type parameters struct {
-		Body    string    `json:"body"`
-		User_id uuid.UUID `json:"user_id"`
+		Body        string      `json:"body"`
+		User_id     uuid.UUID   `json:"user_id"`
+		In_reply_to *uuid.UUID  `json:"in_reply_to"`
+		Media_ids   []uuid.UUID `json:"media_ids"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
 	err := decoder.Decode(&params)
 	if err != nil {
-		log.Fatal("Something went wrong")
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "Could not decode parameters")
 		return
 	}
 
-	jwtToken, err := auth.GetBearerToken(r.Header)
+	cleanedBody, err := cfg.validateChirp(params.Body)
 	if err != nil {
-		w.WriteHeader(401)
-		w.Write([]byte("Error getting bearer Token"))
+		respondWithChirpError(w, err)
 		return
 	}
 
-	userID, err := auth.ValidateJWT(jwtToken, cfg.secret)
-	if err != nil {
-		w.WriteHeader(401)
-		fmt.Println(err)
-		w.Write([]byte("Error getting user_id"))
+	if !cfg.checkMediaIDs(w, r, userID, params.Media_ids) {
 		return
 	}
 
-	chirp, err := cfg.db.CreateChirp(r.Context(), database.CreateChirpParams{Body: params.Body, UserID: userID})
+	inReplyTo := uuid.NullUUID{}
+	if params.In_reply_to != nil {
+		inReplyTo = uuid.NullUUID{UUID: *params.In_reply_to, Valid: true}
+	}
+
+	chirp, err := cfg.createChirp(r.Context(), database.CreateChirpParams{Body: cleanedBody, UserID: userID, InReplyTo: inReplyTo}, params.Media_ids)
 	if err != nil {
-		log.Fatalf("Something went wrong %s", err)
-		w.WriteHeader(400)
-		w.Write([]byte("Error getting chirp"))
+		if errors.Is(err, sql.ErrNoRows) || isForeignKeyViolation(err) {
+			respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "in_reply_to does not refer to an existing chirp")
+			return
+		}
+		if errors.Is(err, errMediaUnavailable) {
+			respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "media_ids must be your own uploads not already attached to a chirp")
+			return
+		}
+		log.Printf("Error creating chirp: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not create chirp")
 		return
 	}
 
-	created_chirp := resp{
-		ID:         chirp.ID,
-		Created_at: chirp.CreatedAt,
-		Updated_at: chirp.UpdatedAt,
-		Body:       chirp.Body,
-		User_id:    chirp.UserID,
+	mentioned, err := cfg.saveChirpEntities(r.Context(), chirp)
+	if err != nil {
+		log.Printf("Error saving entities of chirp %s: %s", chirp.ID, err)
 	}
+	cfg.notifyMentions(r.Context(), chirp, mentioned)
 
-	dat, err := json.Marshal(created_chirp)
-	if err != nil {
-		log.Fatal("Error marshalling JSON")
-		w.WriteHeader(400)
-		return
+	created_chirp := chirpToResp(chirp)
+	if err := cfg.annotateChirps(r, []*resp{&created_chirp}); err != nil {
+		log.Printf("Error resolving mentions: %s", err)
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	w.Write(dat)
+	cfg.publishChirpEvent(chirpEventCreated, chirp.UserID, created_chirp)
 
+	respondWithJSON(w, http.StatusCreated, created_chirp)
+}
+
+// errMediaUnavailable means an upload passed checkMediaIDs but was attached
+// elsewhere or purged before the chirp was saved.
+var errMediaUnavailable = errors.New("media is no longer attachable")
+
+// createChirp inserts a chirp and attaches mediaIDs to it in one transaction,
+// so a chirp is never saved without the media it was posted with.
+func (cfg *apiConfig) createChirp(ctx context.Context, arg database.CreateChirpParams, mediaIDs []uuid.UUID) (database.Chirp, error) {
+	if len(mediaIDs) == 0 {
+		return cfg.db.CreateChirp(ctx, arg)
+	}
+
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	chirp, err := qtx.CreateChirp(ctx, arg)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	n, err := qtx.AttachMedia(ctx, database.AttachMediaParams{
+		ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		Ids:     mediaIDs,
+		UserID:  arg.UserID,
+	})
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	if n != int64(len(mediaIDs)) {
+		return database.Chirp{}, errMediaUnavailable
+	}
+	return chirp, tx.Commit()
 }
 
 func (cfg *apiConfig) handlerGetChirps(w http.ResponseWriter, r *http.Request) {
@@ -84,129 +164,312 @@ func (cfg *apiConfig) handlerGetChirps(w http.ResponseWriter, r *http.Request) {
 	author_id := r.URL.Query().Get("author_id")
 	sortingOrder := r.URL.Query().Get("sort")
 
-	var chirps = make([]database.Chirp, 0)
+	page, ok := parsePageParams(w, r)
+	if !ok {
+		return
+	}
 
+	authorID := uuid.NullUUID{}
 	if author_id != "" {
 		user_id, err := uuid.Parse(author_id)
 		if err != nil {
-			log.Fatal(err)
-		}
-		chirps, err = cfg.db.SelectAllChirpsFromAuthor(r.Context(), user_id)
-		if err != nil {
-			log.Fatal(err)
-		}
-	} else {
-		chirps, _ = cfg.db.SelectAllChirps(r.Context())
-	}
-
-	if sortingOrder == "asc" {
-		sort.Slice(chirps, func(i, j int) bool { return chirps[i].CreatedAt.Before(chirps[j].CreatedAt) })
-	} else if sortingOrder == "desc" {
-		sort.Slice(chirps, func(i, j int) bool { return chirps[i].CreatedAt.After(chirps[j].CreatedAt) })
-	} else {
-		sort.Slice(chirps, func(i, j int) bool { return chirps[i].CreatedAt.Before(chirps[j].CreatedAt) })
-	}
-
-	allChirps := []resp{}
-	for i := range chirps {
-		createdChirp := resp{
-			ID:         chirps[i].ID,
-			Created_at: chirps[i].CreatedAt,
-			Updated_at: chirps[i].UpdatedAt,
-			Body:       chirps[i].Body,
-			User_id:    chirps[i].UserID,
+			respondWithError(w, http.StatusBadRequest, errCodeInvalidID, "Invalid author_id")
+			return
 		}
-		allChirps = append(allChirps, createdChirp)
+		authorID = uuid.NullUUID{UUID: user_id, Valid: true}
 	}
 
-	dat, err := json.Marshal(allChirps)
+	// Fetch one extra row so we know whether there is another page.
+	var chirps []database.Chirp
+	var err error
+	switch sortingOrder {
+	case "likes":
+		if page.cursorTime.Valid && !page.cursorLikeCount.Valid {
+			respondWithError(w, http.StatusBadRequest, errCodeInvalidCursor, "Invalid cursor")
+			return
+		}
+		chirps, err = cfg.db.SelectChirpsPageLikes(r.Context(), database.SelectChirpsPageLikesParams{
+			AuthorID:        authorID,
+			BeforeLikeCount: page.cursorLikeCount,
+			BeforeCreatedAt: page.cursorTime,
+			BeforeID:        page.cursorID,
+			PageSize:        int32(page.limit + 1),
+		})
+	case "desc":
+		chirps, err = cfg.db.SelectChirpsPageDesc(r.Context(), database.SelectChirpsPageDescParams{
+			AuthorID:        authorID,
+			BeforeCreatedAt: page.cursorTime,
+			BeforeID:        page.cursorID,
+			PageSize:        int32(page.limit + 1),
+		})
+	default:
+		chirps, err = cfg.db.SelectChirpsPageAsc(r.Context(), database.SelectChirpsPageAscParams{
+			AuthorID:       authorID,
+			AfterCreatedAt: page.cursorTime,
+			AfterID:        page.cursorID,
+			PageSize:       int32(page.limit + 1),
+		})
+	}
 	if err != nil {
-		log.Fatal(err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get chirps")
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(dat)
+	chirps, nextCursor := pageChirps(chirps, page.limit)
+	cfg.respondWithChirpPage(w, r, newChirpPage(chirps, nextCursor))
 }
 
 func (cfg *apiConfig) handlerGetChirp(w http.ResponseWriter, r *http.Request) {
 
 	id, err := uuid.Parse(r.PathValue("chirpID"))
 	if err != nil {
-		log.Fatal()
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Chirp not found")
+		return
 	}
 
 	chirp, err := cfg.db.SelectChirp(r.Context(), id)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte("Chirp not found!"))
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Chirp not found")
 		return
 	}
 
-	selectedChirp := resp{
-		ID:         chirp.ID,
-		Created_at: chirp.CreatedAt,
-		Updated_at: chirp.UpdatedAt,
-		Body:       chirp.Body,
-		User_id:    chirp.UserID,
+	selectedChirp := chirpToResp(chirp)
+	if err := cfg.annotateChirps(r, []*resp{&selectedChirp}); err != nil {
+		log.Printf("Error annotating chirps: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get chirp")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, selectedChirp)
+}
+
+func (cfg *apiConfig) handleDeleteChirp(w http.ResponseWriter, r *http.Request) {
+	chirp, ok := cfg.authorizeChirpOwner(w, r)
+	if !ok {
+		return
 	}
 
-	dat, err := json.Marshal(selectedChirp)
+	// The chirp is only marked as deleted; purgeDeletedChirps removes it and
+	// its media once the retention period is over.
+	n, err := cfg.db.DeleteChirp(r.Context(), database.DeleteChirpParams{
+		ID:        chirp.ID,
+		DeletedBy: uuid.NullUUID{UUID: chirp.UserID, Valid: true},
+	})
 	if err != nil {
-		w.Header().Set("Context-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte("Chirp not found!"))
-		log.Fatal("Somethin went wrong marshalling json")
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not delete chirp")
+		return
+	}
+	if n == 0 {
+		// Deleted by a concurrent request.
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Chirp not found")
+		return
 	}
 
-	w.Header().Set("Context-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(dat)
+	cfg.publishChirpEvent(chirpEventDeleted, chirp.UserID, struct {
+		ID      uuid.UUID `json:"id"`
+		User_id uuid.UUID `json:"user_id"`
+	}{chirp.ID, chirp.UserID})
+
+	w.WriteHeader(204)
 
 }
 
-func (cfg *apiConfig) handleDeleteChirp(w http.ResponseWriter, r *http.Request) {
-	accessToken, err := auth.GetBearerToken(r.Header)
+// handlerRestoreChirp undoes an owner's delete within cfg.undeleteWindow.
+// Chirps removed by someone else stay removed. Restoring a chirp that is not
+// deleted succeeds and returns it unchanged.
+func (cfg *apiConfig) handlerRestoreChirp(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(r.PathValue("chirpID"))
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Could not find access token"))
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Chirp not found")
 		return
 	}
 
-	user_ID, err := auth.ValidateJWT(accessToken, cfg.secret)
+	chirp, err := cfg.db.SelectChirpIncludingDeleted(r.Context(), id)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Could not find user id"))
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Chirp not found")
+		return
+	}
+	if chirp.UserID != userID {
+		respondWithError(w, http.StatusForbidden, errCodeForbidden, "You don't own the chirp")
 		return
 	}
 
-	chirp_id, err := uuid.Parse(r.PathValue("chirpID"))
+	wasDeleted := chirp.DeletedAt.Valid
+	if wasDeleted {
+		if chirp.DeletedBy.UUID != userID {
+			respondWithError(w, http.StatusForbidden, errCodeForbidden, "The chirp was removed by a moderator")
+			return
+		}
+		if time.Since(chirp.DeletedAt.Time) > cfg.undeleteWindow {
+			respondWithError(w, http.StatusForbidden, errCodeForbidden, "The chirp can no longer be restored")
+			return
+		}
+
+		chirp, err = cfg.db.RestoreChirp(r.Context(), id)
+		if errors.Is(err, sql.ErrNoRows) {
+			// Restored or purged by a concurrent request.
+			respondWithError(w, http.StatusNotFound, errCodeNotFound, "Chirp not found")
+			return
+		}
+		if err != nil {
+			log.Printf("Error restoring chirp %s: %s", id, err)
+			respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not restore chirp")
+			return
+		}
+	}
+
+	restoredChirp := chirpToResp(chirp)
+	if err := cfg.annotateChirps(r, []*resp{&restoredChirp}); err != nil {
+		log.Printf("Error annotating chirps: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get chirp")
+		return
+	}
+
+	if wasDeleted {
+		cfg.publishChirpEvent(chirpEventRestored, chirp.UserID, restoredChirp)
+	}
+
+	respondWithJSON(w, http.StatusOK, restoredChirp)
+}
+
+func (cfg *apiConfig) handleUpdateChirp(w http.ResponseWriter, r *http.Request) {
+	chirp, ok := cfg.authorizeChirpOwner(w, r)
+	if !ok {
+		return
+	}
+
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
 	if err != nil {
-		w.WriteHeader(404)
-		w.Write([]byte("Could not get chirp id"))
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "Could not decode parameters")
 		return
 	}
 
-	chirp, err := cfg.db.SelectChirp(r.Context(), chirp_id)
+	cleanedBody, err := cfg.validateChirp(params.Body)
 	if err != nil {
-		w.WriteHeader(404)
-		w.Write([]byte("Chirp not found"))
+		respondWithChirpError(w, err)
 		return
 	}
 
-	if chirp.UserID != user_ID {
-		w.WriteHeader(403)
-		w.Write([]byte("You dont own the chirp"))
+	updated, err := cfg.db.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+		ID:   chirp.ID,
+		Body: cleanedBody,
+	})
+	if err != nil {
+		log.Printf("Error updating chirp: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not update chirp")
+		return
+	}
+
+	// Only users the edit adds get notified; those already mentioned were
+	// told when they first were.
+	previous, prevErr := cfg.db.SelectChirpMentions(r.Context(), []uuid.UUID{chirp.ID})
+	if prevErr != nil {
+		log.Printf("Error selecting mentions of chirp %s: %s", chirp.ID, prevErr)
+	}
+	mentioned, err := cfg.saveChirpEntities(r.Context(), updated)
+	if err != nil {
+		log.Printf("Error saving entities of chirp %s: %s", updated.ID, err)
+	}
+	if prevErr == nil {
+		wasMentioned := make(map[uuid.UUID]bool, len(previous))
+		for _, m := range previous {
+			wasMentioned[m.UserID] = true
+		}
+		var added []uuid.UUID
+		for _, userID := range mentioned {
+			if !wasMentioned[userID] {
+				added = append(added, userID)
+			}
+		}
+		cfg.notifyMentions(r.Context(), updated, added)
+	}
+
+	updatedChirp := chirpToResp(updated)
+	if err := cfg.annotateChirps(r, []*resp{&updatedChirp}); err != nil {
+		log.Printf("Error annotating chirps: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get chirp")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, updatedChirp)
+}
+
+func (cfg *apiConfig) handlerGetChirpRevisions(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Chirp not found")
 		return
 	}
 
-	err = cfg.db.DeleteChirp(r.Context(), chirp_id)
+	_, err = cfg.db.SelectChirp(r.Context(), id)
 	if err != nil {
-		w.WriteHeader(403)
-		w.Write([]byte("Could not delete chirp"))
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Chirp not found")
 		return
 	}
 
-	w.WriteHeader(204)
+	revisions, err := cfg.db.SelectChirpRevisions(r.Context(), id)
+	if err != nil {
+		log.Printf("Error selecting chirp revisions: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get revisions")
+		return
+	}
+
+	type revision struct {
+		ID          uuid.UUID `json:"id"`
+		Body        string    `json:"body"`
+		Created_at  time.Time `json:"created_at"`
+		Replaced_at time.Time `json:"replaced_at"`
+	}
+
+	allRevisions := []revision{}
+	for _, rev := range revisions {
+		allRevisions = append(allRevisions, revision{
+			ID:          rev.ID,
+			Body:        rev.Body,
+			Created_at:  rev.CreatedAt,
+			Replaced_at: rev.ReplacedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, allRevisions)
+}
+
+// authorizeChirpOwner loads the chirp named in the path and checks that the
+// caller wrote it. It writes the error response itself and returns false if
+// the request should stop.
+func (cfg *apiConfig) authorizeChirpOwner(w http.ResponseWriter, r *http.Request) (database.Chirp, bool) {
+	user_ID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return database.Chirp{}, false
+	}
+
+	chirp_id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Chirp not found")
+		return database.Chirp{}, false
+	}
+
+	chirp, err := cfg.db.SelectChirp(r.Context(), chirp_id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Chirp not found")
+		return database.Chirp{}, false
+	}
+
+	if chirp.UserID != user_ID {
+		respondWithError(w, http.StatusForbidden, errCodeForbidden, "You don't own the chirp")
+		return database.Chirp{}, false
+	}
 
+	return chirp, true
 }
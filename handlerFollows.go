@@ -0,0 +1,192 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Lockenrocky/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type followResp struct {
+	User_id     uuid.UUID `json:"user_id"`
+	Followed_at time.Time `json:"followed_at"`
+}
+
+type followPage struct {
+	Users      []followResp `json:"users"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// handlerFollowUser is idempotent: following someone twice leaves one follow.
+func (cfg *apiConfig) handlerFollowUser(w http.ResponseWriter, r *http.Request) {
+	followerID, followeeID, ok := cfg.followRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.db.FollowUser(r.Context(), database.FollowUserParams{FollowerID: followerID, FolloweeID: followeeID})
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			respondWithError(w, http.StatusNotFound, errCodeNotFound, "User not found")
+			return
+		}
+		log.Printf("Error following user: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not follow user")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerUnfollowUser is idempotent: removing a follow that does not exist
+// succeeds.
+func (cfg *apiConfig) handlerUnfollowUser(w http.ResponseWriter, r *http.Request) {
+	followerID, followeeID, ok := cfg.followRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.db.UnfollowUser(r.Context(), database.UnfollowUserParams{FollowerID: followerID, FolloweeID: followeeID})
+	if err != nil {
+		log.Printf("Error unfollowing user: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not unfollow user")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) followRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	followerID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "User not found")
+		return uuid.Nil, uuid.Nil, false
+	}
+	if followeeID == followerID {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "Users cannot follow themselves")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return followerID, followeeID, true
+}
+
+func (cfg *apiConfig) handlerGetFollowers(w http.ResponseWriter, r *http.Request) {
+	userID, page, ok := cfg.followListRequest(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := cfg.db.SelectFollowersPage(r.Context(), database.SelectFollowersPageParams{
+		UserID:          userID,
+		BeforeCreatedAt: page.cursorTime,
+		BeforeID:        page.cursorID,
+		PageSize:        int32(page.limit + 1),
+	})
+	if err != nil {
+		log.Printf("Error getting followers: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get followers")
+		return
+	}
+
+	follows := make([]followResp, len(rows))
+	for i, row := range rows {
+		follows[i] = followResp{User_id: row.UserID, Followed_at: row.CreatedAt}
+	}
+	respondWithJSON(w, http.StatusOK, pageFollows(follows, page.limit))
+}
+
+func (cfg *apiConfig) handlerGetFollowing(w http.ResponseWriter, r *http.Request) {
+	userID, page, ok := cfg.followListRequest(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := cfg.db.SelectFollowingPage(r.Context(), database.SelectFollowingPageParams{
+		UserID:          userID,
+		BeforeCreatedAt: page.cursorTime,
+		BeforeID:        page.cursorID,
+		PageSize:        int32(page.limit + 1),
+	})
+	if err != nil {
+		log.Printf("Error getting followed users: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get followed users")
+		return
+	}
+
+	follows := make([]followResp, len(rows))
+	for i, row := range rows {
+		follows[i] = followResp{User_id: row.UserID, Followed_at: row.CreatedAt}
+	}
+	respondWithJSON(w, http.StatusOK, pageFollows(follows, page.limit))
+}
+
+func (cfg *apiConfig) followListRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, pageParams, bool) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "User not found")
+		return uuid.Nil, pageParams{}, false
+	}
+
+	page, ok := parsePageParams(w, r)
+	if !ok {
+		return uuid.Nil, pageParams{}, false
+	}
+
+	if _, err := cfg.db.SelectUserID(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "User not found")
+		return uuid.Nil, pageParams{}, false
+	}
+
+	return userID, page, true
+}
+
+// pageFollows trims a result fetched with limit+1 rows down to limit. Follow
+// listings are newest-first and keyed on (followed_at, user_id).
+func pageFollows(follows []followResp, limit int) followPage {
+	if len(follows) <= limit {
+		return followPage{Users: follows}
+	}
+
+	follows = follows[:limit]
+	last := follows[len(follows)-1]
+	return followPage{
+		Users:      follows,
+		NextCursor: encodeCursor(chirpCursor{CreatedAt: last.Followed_at, ID: last.User_id}),
+	}
+}
+
+// handlerGetTimeline returns chirps from the accounts the caller follows,
+// newest first.
+func (cfg *apiConfig) handlerGetTimeline(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	page, ok := parsePageParams(w, r)
+	if !ok {
+		return
+	}
+
+	chirps, err := cfg.db.SelectTimelinePage(r.Context(), database.SelectTimelinePageParams{
+		FollowerID:      userID,
+		BeforeCreatedAt: page.cursorTime,
+		BeforeID:        page.cursorID,
+		PageSize:        int32(page.limit + 1),
+	})
+	if err != nil {
+		log.Printf("Error getting timeline: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get timeline")
+		return
+	}
+
+	chirps, next := pageChirps(chirps, page.limit)
+	cfg.respondWithChirpPage(w, r, newChirpPage(chirps, next))
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lockenrocky/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// seedTimeline adds a user following followees accounts that have posted
+// posts chirps each over the past year, and returns the follower's ID.
+func seedTimeline(tb testing.TB, conn *sql.DB, followees, posts int) uuid.UUID {
+	tb.Helper()
+	follower := uuid.New()
+	steps := []struct {
+		query string
+		args  []any
+	}{
+		{`INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+		  VALUES ($1, NOW(), NOW(), 'follower@example.com', 'unused')`, []any{follower}},
+		{`INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+		  SELECT gen_random_uuid(), NOW(), NOW(), 'followee' || i || '@example.com', 'unused'
+		  FROM generate_series(1, $1::int) AS i`, []any{followees}},
+		{`INSERT INTO follows (follower_id, followee_id, created_at)
+		  SELECT $1::uuid, users.id, NOW() FROM users WHERE users.id <> $1::uuid`, []any{follower}},
+		{`INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+		  SELECT gen_random_uuid(), NOW() - random() * interval '365 days', NOW(), 'chirp ' || n, users.id
+		  FROM users, generate_series(1, $2::int) AS n
+		  WHERE users.id <> $1::uuid`, []any{follower, posts}},
+		{`ANALYZE users, follows, chirps`, nil},
+	}
+	for _, step := range steps {
+		if _, err := conn.Exec(step.query, step.args...); err != nil {
+			tb.Fatalf("seeding timeline: %v\n%s", err, step.query)
+		}
+	}
+	return follower
+}
+
+func TestSelectTimelinePage(t *testing.T) {
+	conn := openTestDB(t)
+	follower := seedTimeline(t, conn, 30, 15)
+	db := database.New(conn)
+	ctx := context.Background()
+
+	var want []database.Chirp
+	rows, err := conn.Query(`SELECT id, created_at FROM chirps ORDER BY created_at DESC, id DESC`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var c database.Chirp
+		if err := rows.Scan(&c.ID, &c.CreatedAt); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, c)
+	}
+	rows.Close()
+
+	// Page through everything with a page size smaller than each followee's
+	// post count, so every page needs chirps from more than one of them.
+	var got []database.Chirp
+	params := database.SelectTimelinePageParams{FollowerID: follower, PageSize: 7}
+	for {
+		page, err := db.SelectTimelinePage(ctx, params)
+		if err != nil {
+			t.Fatalf("SelectTimelinePage() error = %v", err)
+		}
+		got = append(got, page...)
+		if len(page) < int(params.PageSize) {
+			break
+		}
+		last := page[len(page)-1]
+		params.BeforeCreatedAt = sql.NullTime{Time: last.CreatedAt, Valid: true}
+		params.BeforeID = uuid.NullUUID{UUID: last.ID, Valid: true}
+	}
+
+	sameChirp := func(a, b database.Chirp) bool { return a.ID == b.ID }
+	if !slices.EqualFunc(got, want, sameChirp) {
+		t.Errorf("paged timeline has %d chirps, want all %d newest first", len(got), len(want))
+	}
+}
+
+// explainDB logs the plan of each query before running it.
+type explainDB struct {
+	*sql.DB
+	tb testing.TB
+}
+
+func (e explainDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	rows, err := e.DB.QueryContext(ctx, "EXPLAIN (ANALYZE, BUFFERS) "+query, args...)
+	if err != nil {
+		return nil, err
+	}
+	var plan []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		plan = append(plan, line)
+	}
+	rows.Close()
+	e.tb.Logf("plan:\n%s", strings.Join(plan, "\n"))
+	return e.DB.QueryContext(ctx, query, args...)
+}
+
+// BenchmarkSelectTimelinePage reads timeline pages for a user following 5,000
+// accounts with 50 chirps each. It needs a scratch database:
+//
+//	CHIRPY_TEST_DB_URL='postgres://localhost/chirpy_test?sslmode=disable' \
+//		go test -run '^$' -bench SelectTimelinePage -v
+//
+// With -v the plan of each case is logged as well.
+func BenchmarkSelectTimelinePage(b *testing.B) {
+	conn := openTestDB(b)
+	follower := seedTimeline(b, conn, 5000, 50)
+	ctx := context.Background()
+
+	cases := []struct {
+		name   string
+		before sql.NullTime
+	}{
+		{"First page", sql.NullTime{}},
+		{"Six months back", sql.NullTime{Time: time.Now().UTC().AddDate(0, -6, 0), Valid: true}},
+	}
+	for _, c := range cases {
+		params := database.SelectTimelinePageParams{
+			FollowerID:      follower,
+			BeforeCreatedAt: c.before,
+			BeforeID:        uuid.NullUUID{Valid: c.before.Valid},
+			PageSize:        defaultPageSize + 1,
+		}
+		b.Run(c.name, func(b *testing.B) {
+			if _, err := database.New(explainDB{conn, b}).SelectTimelinePage(ctx, params); err != nil {
+				b.Fatalf("SelectTimelinePage() error = %v", err)
+			}
+			db := database.New(conn)
+			for b.Loop() {
+				if _, err := db.SelectTimelinePage(ctx, params); err != nil {
+					b.Fatalf("SelectTimelinePage() error = %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Lockenrocky/chirpy/internal/database"
+	"github.com/Lockenrocky/chirpy/internal/entities"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	maxTrendingWindow     = 7 * 24 * time.Hour
+)
+
+// entity is a hashtag or mention in a chirp body. Start and End are code
+// point offsets into the body, End exclusive, covering the # or @.
+type entity struct {
+	Type    entities.Type `json:"type"`
+	Start   int           `json:"start"`
+	End     int           `json:"end"`
+	Text    string        `json:"text"`
+	User_id *uuid.UUID    `json:"user_id,omitempty"`
+}
+
+type trendingHashtag struct {
+	Tag        string `json:"tag"`
+	ChirpCount int64  `json:"chirp_count"`
+}
+
+func bodyEntities(body string) []entity {
+	found := entities.Parse(body)
+	out := make([]entity, len(found))
+	for i, e := range found {
+		out[i] = entity{Type: e.Type, Start: e.Start, End: e.End, Text: e.Text}
+	}
+	return out
+}
+
+// saveChirpEntities replaces the stored hashtags and mentions of a chirp with
+// those in its current body and returns the users it mentions.
+func (cfg *apiConfig) saveChirpEntities(ctx context.Context, chirp database.Chirp) ([]uuid.UUID, error) {
+	if err := cfg.db.DeleteChirpEntities(ctx, chirp.ID); err != nil {
+		return nil, err
+	}
+
+	if tags := entities.Hashtags(chirp.Body); len(tags) > 0 {
+		err := cfg.db.InsertChirpHashtags(ctx, database.InsertChirpHashtagsParams{Tags: tags, ChirpID: chirp.ID})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	handles := entities.Mentions(chirp.Body)
+	if len(handles) == 0 {
+		return nil, nil
+	}
+	return cfg.db.InsertChirpMentions(ctx, database.InsertChirpMentionsParams{ChirpID: chirp.ID, Handles: handles})
+}
+
+// resolveMentions fills in User_id on the mention entities of chirps and
+// drops mentions that do not refer to a single user.
+func (cfg *apiConfig) resolveMentions(r *http.Request, chirps []*resp) error {
+	var ids []uuid.UUID
+	for _, chirp := range chirps {
+		for _, e := range chirp.Entities {
+			if e.Type == entities.TypeMention {
+				ids = append(ids, chirp.ID)
+				break
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	mentions, err := cfg.db.SelectChirpMentions(r.Context(), ids)
+	if err != nil {
+		return err
+	}
+
+	type key struct {
+		chirpID uuid.UUID
+		handle  string
+	}
+	users := make(map[key]uuid.UUID, len(mentions))
+	for _, m := range mentions {
+		users[key{m.ChirpID, m.Handle}] = m.UserID
+	}
+
+	for _, chirp := range chirps {
+		kept := chirp.Entities[:0]
+		for _, e := range chirp.Entities {
+			if e.Type == entities.TypeMention {
+				userID, ok := users[key{chirp.ID, e.Text}]
+				if !ok {
+					continue
+				}
+				e.User_id = &userID
+			}
+			kept = append(kept, e)
+		}
+		chirp.Entities = kept
+	}
+	return nil
+}
+
+func (cfg *apiConfig) handlerGetHashtagChirps(w http.ResponseWriter, r *http.Request) {
+	tag := strings.ToLower(strings.TrimPrefix(r.PathValue("tag"), "#"))
+
+	page, ok := parsePageParams(w, r)
+	if !ok {
+		return
+	}
+
+	chirps, err := cfg.db.SelectHashtagChirpsPage(r.Context(), database.SelectHashtagChirpsPageParams{
+		Tag:             tag,
+		BeforeCreatedAt: page.cursorTime,
+		BeforeID:        page.cursorID,
+		PageSize:        int32(page.limit + 1),
+	})
+	if err != nil {
+		log.Printf("Error getting chirps for #%s: %s", tag, err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get chirps")
+		return
+	}
+
+	chirps, next := pageChirps(chirps, page.limit)
+	cfg.respondWithChirpPage(w, r, newChirpPage(chirps, next))
+}
+
+// handlerTrendingHashtags ranks hashtags by how many chirps used them within
+// the trailing window, 24h unless ?window= gives another duration.
+func (cfg *apiConfig) handlerTrendingHashtags(w http.ResponseWriter, r *http.Request) {
+	window := defaultTrendingWindow
+	if s := r.URL.Query().Get("window"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 || d > maxTrendingWindow {
+			respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "window must be a positive duration of at most "+maxTrendingWindow.String())
+			return
+		}
+		window = d
+	}
+
+	limit, err := parsePageSize(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "limit must be a positive integer")
+		return
+	}
+
+	rows, err := cfg.db.SelectTrendingHashtags(r.Context(), database.SelectTrendingHashtagsParams{
+		Since:    time.Now().UTC().Add(-window),
+		PageSize: int32(limit),
+	})
+	if err != nil {
+		log.Printf("Error getting trending hashtags: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get trending hashtags")
+		return
+	}
+
+	trending := make([]trendingHashtag, len(rows))
+	for i, row := range rows {
+		trending[i] = trendingHashtag{Tag: row.Tag, ChirpCount: row.ChirpCount}
+	}
+	respondWithJSON(w, http.StatusOK, trending)
+}
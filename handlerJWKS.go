@@ -0,0 +1,17 @@
+package main
+
+import (
+	"net/http"
+)
+
+// jwksMaxAge is how long other services may cache the key set. A new signing
+// key can be listed as a previous key for this long before it is made
+// current, so no verifier sees a kid it has not fetched yet.
+const jwksMaxAge = "300"
+
+// handlerJWKS serves the public keys access tokens can be verified with, so
+// other services can check Chirpy tokens without holding a secret.
+func (cfg *apiConfig) handlerJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age="+jwksMaxAge)
+	respondWithJSON(w, http.StatusOK, cfg.jwtKeys.JWKS())
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestJWKSEndpoint(t *testing.T) {
+	cfg := newTestConfig(t)
+	rec := httptest.NewRecorder()
+	cfg.routes(".").ServeHTTP(rec, httptest.NewRequest("GET", "/.well-known/jwks.json", nil))
+
+	if rec.Code != 200 {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "public, max-age="+jwksMaxAge {
+		t.Errorf("Cache-Control = %q", got)
+	}
+	var body struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Keys == nil {
+		t.Errorf("body = %s, want a key set", rec.Body)
+	}
+	if len(body.Keys) != 0 {
+		t.Errorf("HMAC test config publishes %d keys, want 0", len(body.Keys))
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/Lockenrocky/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// handlerLikeChirp is idempotent: liking a chirp twice leaves one like.
+func (cfg *apiConfig) handlerLikeChirp(w http.ResponseWriter, r *http.Request) {
+	userID, chirpID, ok := cfg.likeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.db.LikeChirp(r.Context(), database.LikeChirpParams{UserID: userID, ChirpID: chirpID})
+	if err != nil {
+		log.Printf("Error liking chirp: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not like chirp")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerUnlikeChirp is idempotent: removing a like that does not exist
+// succeeds.
+func (cfg *apiConfig) handlerUnlikeChirp(w http.ResponseWriter, r *http.Request) {
+	userID, chirpID, ok := cfg.likeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.db.UnlikeChirp(r.Context(), database.UnlikeChirpParams{UserID: userID, ChirpID: chirpID})
+	if err != nil {
+		log.Printf("Error unliking chirp: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not unlike chirp")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) likeRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Chirp not found")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	_, err = cfg.db.SelectChirp(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Chirp not found")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, chirpID, true
+}
+
+// markLikedByMe fills in Liked_by_me on chirps for the user making the
+// request. Anonymous requests leave every chirp unliked.
+func (cfg *apiConfig) markLikedByMe(r *http.Request, chirps []*resp) error {
+	userID, ok := cfg.optionalUser(r)
+	if !ok || len(chirps) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		ids[i] = chirp.ID
+	}
+
+	liked, err := cfg.db.SelectLikedChirpIDs(r.Context(), database.SelectLikedChirpIDsParams{
+		UserID:   userID,
+		ChirpIds: ids,
+	})
+	if err != nil {
+		return err
+	}
+
+	likedSet := make(map[uuid.UUID]struct{}, len(liked))
+	for _, id := range liked {
+		likedSet[id] = struct{}{}
+	}
+	for _, chirp := range chirps {
+		_, chirp.Liked_by_me = likedSet[chirp.ID]
+	}
+	return nil
+}
@@ -2,9 +2,9 @@ package main
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 	"time"
+	"unicode/utf8"
 
 	"github.com/Lockenrocky/chirpy/internal/auth"
 	"github.com/Lockenrocky/chirpy/internal/database"
@@ -16,43 +16,60 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 		Password         string `json:"password"`
 		Email            string `json:"email"`
 		ExpiresInSeconds int    `json:"expires_in_seconds"`
+		DeviceName       string `json:"device_name"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
-	decoder.Decode(&params)
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "Could not decode parameters")
+		return
+	}
+	if utf8.RuneCountInString(params.DeviceName) > maxDeviceName {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "device_name must be at most 100 characters")
+		return
+	}
 
 	user, err := cfg.db.Login(r.Context(), params.Email)
 	if err != nil {
-		w.Header().Set("Context-Type", "plain/text")
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Incorrect email or password"))
+		respondWithError(w, http.StatusUnauthorized, errCodeInvalidCredentials, "Incorrect email or password")
 		return
 	}
 
 	if auth.CheckPasswordHash(user.HashedPassword, params.Password) != nil {
-		w.Header().Set("Context-Type", "plain/text")
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Incorrect email or password"))
+		respondWithError(w, http.StatusUnauthorized, errCodeInvalidCredentials, "Incorrect email or password")
+		return
+	}
+	if accountRestricted(user.Status, user.SuspendedUntil) {
+		respondAccountRestricted(w, user.Status, user.SuspendedUntil)
 		return
 	}
 
-	jwtToken, err := auth.MakeJWT(user.ID, cfg.secret, time.Hour)
+	jwtToken, err := auth.MakeJWT(user.ID, auth.Role(user.Role), cfg.jwtKeys, cfg.accessTokenTTL)
 	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not create access token")
 		return
 	}
 
-	refToken, _ := auth.MakeRefreshToken()
+	refToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not create refresh token")
+		return
+	}
 
 	_, err = cfg.db.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
-		UserID:    user.ID,
-		Token:     refToken,
-		ExpiresAt: time.Now().UTC().Add(time.Hour * 24 * 60),
+		UserID:     user.ID,
+		TokenHash:  auth.HashRefreshToken(refToken),
+		ExpiresAt:  time.Now().UTC().Add(cfg.refreshTokenTTL),
+		DeviceName: params.DeviceName,
+		UserAgent:  clientUserAgent(r),
+		IpAddress:  clientIP(r),
 	})
 
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("Could not save refresh token"))
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not save refresh token")
+		return
 	}
 
 	type resp struct {
@@ -63,6 +80,7 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 		Token         string    `json:"token"`
 		Refresh_token string    `json:"refresh_token"`
 		IsChirpyRed   bool      `json:"is_chirpy_red"`
+		Role          string    `json:"role"`
 	}
 
 	loggedin_user := resp{
@@ -73,15 +91,8 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 		Token:         jwtToken,
 		Refresh_token: refToken,
 		IsChirpyRed:   user.IsChirpyRed,
+		Role:          user.Role,
 	}
 
-	dat, err := json.Marshal(loggedin_user)
-	if err != nil {
-		log.Fatal("Error marshaling json")
-	}
-
-	w.Header().Set("Context-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(dat)
-
+	respondWithJSON(w, http.StatusOK, loggedin_user)
 }
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+
+	"github.com/Lockenrocky/chirpy/internal/database"
+	"github.com/Lockenrocky/chirpy/internal/media"
+	"github.com/google/uuid"
+)
+
+const (
+	maxUploadBytes = 5 << 20
+	maxChirpMedia  = 4
+	mediaURLPrefix = "/media/"
+	// mediaMaxAge bounds how long a cache keeps serving a file after its
+	// chirp is deleted.
+	mediaMaxAge = "300"
+)
+
+type mediaResp struct {
+	ID            uuid.UUID `json:"id"`
+	Content_type  string    `json:"content_type"`
+	Width         int32     `json:"width"`
+	Height        int32     `json:"height"`
+	URL           string    `json:"url"`
+	Thumbnail_url string    `json:"thumbnail_url"`
+}
+
+func mediaToResp(m database.Medium) mediaResp {
+	return mediaResp{
+		ID:            m.ID,
+		Content_type:  m.ContentType,
+		Width:         m.Width,
+		Height:        m.Height,
+		URL:           mediaURLPrefix + m.FileKey,
+		Thumbnail_url: mediaURLPrefix + m.ThumbnailKey,
+	}
+}
+
+// handlerUploadMedia accepts one image as the "file" field of a multipart
+// form. The returned ID can be passed in media_ids when creating a chirp.
+func (cfg *apiConfig) handlerUploadMedia(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	// Leave room for the multipart framing around the file itself.
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes+64<<10)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondWithError(w, http.StatusRequestEntityTooLarge, errCodeMediaTooLarge, "Upload is larger than 5 MiB")
+			return
+		}
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "Expected a multipart form with a file field")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxUploadBytes+1))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "Could not read upload")
+		return
+	}
+	if len(data) > maxUploadBytes {
+		respondWithError(w, http.StatusRequestEntityTooLarge, errCodeMediaTooLarge, "Upload is larger than 5 MiB")
+		return
+	}
+
+	img, err := media.Process(data)
+	if errors.Is(err, media.ErrUnsupportedType) {
+		respondWithError(w, http.StatusUnsupportedMediaType, errCodeUnsupportedMedia, "Only JPEG, PNG and GIF images are supported")
+		return
+	}
+	if errors.Is(err, media.ErrTooLarge) {
+		respondWithError(w, http.StatusRequestEntityTooLarge, errCodeMediaTooLarge, "Image dimensions are too large")
+		return
+	}
+	if err != nil {
+		log.Printf("Error processing upload: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not process image")
+		return
+	}
+
+	id := uuid.New()
+	fileKey := id.String() + "." + img.Ext
+	thumbKey := id.String() + "_thumb." + img.ThumbnailExt
+	for key, data := range map[string][]byte{fileKey: img.Data, thumbKey: img.Thumbnail} {
+		if err := cfg.media.Put(r.Context(), key, bytes.NewReader(data)); err != nil {
+			log.Printf("Error storing %s: %s", key, err)
+			respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not store image")
+			return
+		}
+	}
+
+	m, err := cfg.db.CreateMedia(r.Context(), database.CreateMediaParams{
+		ID:           id,
+		UserID:       userID,
+		ContentType:  img.ContentType,
+		FileKey:      fileKey,
+		ThumbnailKey: thumbKey,
+		Width:        int32(img.Width),
+		Height:       int32(img.Height),
+		SizeBytes:    int32(len(img.Data)),
+	})
+	if err != nil {
+		log.Printf("Error saving media: %s", err)
+		cfg.deleteMediaFiles(r.Context(), fileKey, thumbKey)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not store image")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, mediaToResp(m))
+}
+
+// handlerServeMedia serves stored files. Files of deleted or hidden chirps
+// are not served, so responses are only cached for mediaMaxAge; the ETag
+// makes revalidating them cheap.
+func (cfg *apiConfig) handlerServeMedia(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	_, err := cfg.db.SelectDeletedChirpMedia(r.Context(), key)
+	if err == nil {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Media not found")
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Error checking %s: %s", key, err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not read media")
+		return
+	}
+
+	obj, err := cfg.media.Open(r.Context(), key)
+	if errors.Is(err, media.ErrNotFound) || errors.Is(err, media.ErrInvalidKey) {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Media not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error opening %s: %s", key, err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not read media")
+		return
+	}
+	defer obj.Close()
+
+	w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(key)))
+	w.Header().Set("Cache-Control", "public, max-age="+mediaMaxAge)
+	w.Header().Set("ETag", `"`+key+`"`)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, key, obj.ModTime, obj)
+}
+
+func (cfg *apiConfig) deleteMediaFiles(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := cfg.media.Delete(ctx, key); err != nil {
+			log.Printf("Error deleting %s: %s", key, err)
+		}
+	}
+}
+
+// checkMediaIDs reports whether ids can be attached to a new chirp by
+// userID: at most maxChirpMedia, no repeats, and each an unattached upload
+// of theirs. It writes the error response itself.
+func (cfg *apiConfig) checkMediaIDs(w http.ResponseWriter, r *http.Request, userID uuid.UUID, ids []uuid.UUID) bool {
+	if len(ids) == 0 {
+		return true
+	}
+	if len(ids) > maxChirpMedia {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "A chirp can have at most 4 attachments")
+		return false
+	}
+	seen := map[uuid.UUID]bool{}
+	for _, id := range ids {
+		if seen[id] {
+			respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "media_ids has duplicates")
+			return false
+		}
+		seen[id] = true
+	}
+
+	n, err := cfg.db.CountAttachableMedia(r.Context(), database.CountAttachableMediaParams{Ids: ids, UserID: userID})
+	if err != nil {
+		log.Printf("Error checking media: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not create chirp")
+		return false
+	}
+	if n != int64(len(ids)) {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "media_ids must be your own uploads not already attached to a chirp")
+		return false
+	}
+	return true
+}
+
+// attachMediaResp fills in Media on chirps.
+func (cfg *apiConfig) attachMediaResp(r *http.Request, chirps []*resp) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		ids[i] = chirp.ID
+	}
+
+	rows, err := cfg.db.SelectChirpMedia(r.Context(), ids)
+	if err != nil {
+		return err
+	}
+
+	byChirp := map[uuid.UUID][]mediaResp{}
+	for _, m := range rows {
+		byChirp[m.ChirpID.UUID] = append(byChirp[m.ChirpID.UUID], mediaToResp(m))
+	}
+	for _, chirp := range chirps {
+		if m, ok := byChirp[chirp.ID]; ok {
+			chirp.Media = m
+		}
+	}
+	return nil
+}
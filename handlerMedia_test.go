@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lockenrocky/chirpy/internal/auth"
+	"github.com/Lockenrocky/chirpy/internal/media"
+	"github.com/google/uuid"
+)
+
+func multipartUpload(t *testing.T, data []byte) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", "upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(data)
+	mw.Close()
+	return &body, mw.FormDataContentType()
+}
+
+func TestUploadMediaErrors(t *testing.T) {
+	token, err := auth.MakeJWT(uuid.New(), auth.RoleUser, testKeys, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+
+	var pngData bytes.Buffer
+	png.Encode(&pngData, image.NewGray(image.Rect(0, 0, 4, 4)))
+
+	tests := []struct {
+		name       string
+		data       []byte
+		wantStatus int
+		wantCode   string
+	}{
+		{"not an image", []byte("<svg onload=alert(1)></svg>"), http.StatusUnsupportedMediaType, errCodeUnsupportedMedia},
+		{"too large", bytes.Repeat([]byte{0}, maxUploadBytes+1), http.StatusRequestEntityTooLarge, errCodeMediaTooLarge},
+		// The test database cannot save the row, so the stored files must be
+		// cleaned up again.
+		{"database error", pngData.Bytes(), http.StatusInternalServerError, errCodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig(t)
+			dir := t.TempDir()
+			storage, err := media.NewLocal(dir)
+			if err != nil {
+				t.Fatalf("media.NewLocal() error = %v", err)
+			}
+			cfg.media = storage
+
+			body, contentType := multipartUpload(t, tt.data)
+			req := httptest.NewRequest("POST", "/api/media", body)
+			req.Header.Set("Authorization", "Bearer "+token)
+			req.Header.Set("Content-Type", contentType)
+			rec := httptest.NewRecorder()
+			cfg.routes(".").ServeHTTP(rec, req)
+
+			assertErrorEnvelope(t, rec, tt.wantStatus, tt.wantCode)
+			if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+				t.Errorf("media directory has %d entries after a failed upload", len(entries))
+			}
+		})
+	}
+}
+
+func TestServeMediaCaching(t *testing.T) {
+	cfg := newTestConfig(t)
+	key := uuid.NewString() + ".png"
+	if err := cfg.media.Put(context.Background(), key, strings.NewReader("png bytes")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	mux := cfg.routes(".")
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/media/"+key, nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "png bytes" {
+		t.Fatalf("GET = %d %q, want 200 with the file", rec.Code, rec.Body.String())
+	}
+	for header, want := range map[string]string{
+		"Content-Type":           "image/png",
+		"Cache-Control":          "public, max-age=" + mediaMaxAge,
+		"X-Content-Type-Options": "nosniff",
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/media/"+key, nil)
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("conditional GET = %d, want 304", rec.Code)
+	}
+}
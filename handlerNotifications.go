@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Lockenrocky/chirpy/internal/database"
+	"github.com/Lockenrocky/chirpy/internal/pubsub"
+	"github.com/google/uuid"
+)
+
+// notificationEvent tells a connected user they have a new notification.
+// Only live connections see these, so none are kept for replay.
+type notificationEvent struct {
+	UserID uuid.UUID
+	Type   string
+	Data   []byte
+}
+
+func newNotificationEvents() *pubsub.Broker[notificationEvent] {
+	return pubsub.New[notificationEvent](0, chirpEventBuffer)
+}
+
+// publishMentions tells the users mentioned in chirp, other than its author,
+// about their new notification.
+func (cfg *apiConfig) publishMentions(chirp database.Chirp, mentioned []uuid.UUID) {
+	data, err := json.Marshal(struct {
+		Chirp_id uuid.UUID `json:"chirp_id"`
+		Actor_id uuid.UUID `json:"actor_id"`
+	}{chirp.ID, chirp.UserID})
+	if err != nil {
+		log.Printf("Error encoding mention event: %s", err)
+		return
+	}
+	for _, userID := range mentioned {
+		if userID != chirp.UserID {
+			cfg.notificationEvents.Publish(notificationEvent{UserID: userID, Type: "mention", Data: data})
+		}
+	}
+}
+
+// notifyMentions creates mention notifications for the users in mentioned
+// and publishes them. Errors are logged: the chirp is saved either way.
+func (cfg *apiConfig) notifyMentions(ctx context.Context, chirp database.Chirp, mentioned []uuid.UUID) {
+	if len(mentioned) == 0 {
+		return
+	}
+	err := cfg.db.CreateMentionNotifications(ctx, database.CreateMentionNotificationsParams{
+		ChirpID: chirp.ID,
+		ActorID: chirp.UserID,
+		UserIds: mentioned,
+	})
+	if err != nil {
+		log.Printf("Error notifying mentions of chirp %s: %s", chirp.ID, err)
+		return
+	}
+	cfg.publishMentions(chirp, mentioned)
+}
+
+type notificationResp struct {
+	ID         uuid.UUID  `json:"id"`
+	Type       string     `json:"type"`
+	Chirp_id   uuid.UUID  `json:"chirp_id"`
+	Actor_id   uuid.UUID  `json:"actor_id"`
+	Created_at time.Time  `json:"created_at"`
+	Read_at    *time.Time `json:"read_at"`
+}
+
+type notificationPage struct {
+	Notifications []notificationResp `json:"notifications"`
+	UnreadCount   int64              `json:"unread_count"`
+	NextCursor    string             `json:"next_cursor,omitempty"`
+}
+
+// handlerGetNotifications lists the caller's notifications newest first.
+// ?unread=true leaves out those already read.
+func (cfg *apiConfig) handlerGetNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	page, ok := parsePageParams(w, r)
+	if !ok {
+		return
+	}
+
+	notifications, err := cfg.db.SelectNotificationsPage(r.Context(), database.SelectNotificationsPageParams{
+		UserID:          userID,
+		UnreadOnly:      r.URL.Query().Get("unread") == "true",
+		BeforeCreatedAt: page.cursorTime,
+		BeforeID:        page.cursorID,
+		PageSize:        int32(page.limit + 1),
+	})
+	if err != nil {
+		log.Printf("Error getting notifications: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get notifications")
+		return
+	}
+
+	unread, err := cfg.db.CountUnreadNotifications(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error counting notifications: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get notifications")
+		return
+	}
+
+	result := notificationPage{Notifications: []notificationResp{}, UnreadCount: unread}
+	if len(notifications) > page.limit {
+		notifications = notifications[:page.limit]
+		last := notifications[len(notifications)-1]
+		result.NextCursor = encodeCursor(chirpCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	for _, n := range notifications {
+		item := notificationResp{
+			ID:         n.ID,
+			Type:       n.Type,
+			Chirp_id:   n.ChirpID,
+			Actor_id:   n.ActorID,
+			Created_at: n.CreatedAt,
+		}
+		if n.ReadAt.Valid {
+			readAt := n.ReadAt.Time
+			item.Read_at = &readAt
+		}
+		result.Notifications = append(result.Notifications, item)
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// handlerMarkNotificationRead is idempotent: marking a read notification
+// again keeps its original read_at.
+func (cfg *apiConfig) handlerMarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(r.PathValue("notificationID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Notification not found")
+		return
+	}
+
+	n, err := cfg.db.MarkNotificationRead(r.Context(), database.MarkNotificationReadParams{ID: id, UserID: userID})
+	if err != nil {
+		log.Printf("Error marking notification read: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not update notification")
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Notification not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerMarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	if err := cfg.db.MarkAllNotificationsRead(r.Context(), userID); err != nil {
+		log.Printf("Error marking notifications read: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not update notifications")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
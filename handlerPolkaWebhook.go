@@ -1,6 +1,7 @@
 package main
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"net/http"
 
@@ -12,14 +13,12 @@ func (cfg *apiConfig) handlePolkaWebhooks(w http.ResponseWriter, r *http.Request
 
 	apiKey, err := auth.GetAPIKey(r.Header)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Could find ApiKey in header"))
+		respondWithError(w, http.StatusUnauthorized, errCodeMissingToken, "Could not find API key in header")
 		return
 	}
 
-	if apiKey != cfg.apiKey {
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Wrong ApiKey"))
+	if cfg.apiKey == "" || subtle.ConstantTimeCompare([]byte(apiKey), []byte(cfg.apiKey)) != 1 {
+		respondWithError(w, http.StatusUnauthorized, errCodeInvalidAPIKey, "Wrong API key")
 		return
 	}
 
@@ -34,8 +33,7 @@ func (cfg *apiConfig) handlePolkaWebhooks(w http.ResponseWriter, r *http.Request
 	params := parameters{}
 	err = decoder.Decode(&params)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Bad Request"))
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "Could not decode parameters")
 		return
 	}
 
@@ -45,8 +43,8 @@ func (cfg *apiConfig) handlePolkaWebhooks(w http.ResponseWriter, r *http.Request
 	} else {
 		_, err = cfg.db.UpgradeToChirpyRed(r.Context(), params.Data.User_id)
 		if err != nil {
-			w.WriteHeader(404)
-			w.Write([]byte("Could not update user"))
+			respondWithError(w, http.StatusNotFound, errCodeNotFound, "User not found")
+			return
 		}
 	}
 
@@ -1,50 +1,108 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"database/sql"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
 	"github.com/Lockenrocky/chirpy/internal/auth"
+	"github.com/Lockenrocky/chirpy/internal/database"
 )
 
+// handlerRefresh exchanges a refresh token for a new access token and a new
+// refresh token. The presented token is revoked, so each one works once;
+// presenting one that has already been rotated means it was copied, and
+// every token in its family is revoked. The new token continues the same
+// session, so its last_used_at is when the session was last refreshed.
 func (cfg *apiConfig) handlerRefresh(w http.ResponseWriter, r *http.Request) {
 	refreshToken, err := auth.GetBearerToken(r.Header)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Could not find token"))
+		respondWithError(w, http.StatusUnauthorized, errCodeMissingToken, "Could not find token")
 		return
 	}
 
-	user, err := cfg.db.GetUserFromRefreshToken(r.Context(), refreshToken)
+	tokenHash := auth.HashRefreshToken(refreshToken)
+	user, err := cfg.db.GetUserFromRefreshToken(r.Context(), tokenHash)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Could not get user from refresh token"))
+		cfg.respondInvalidRefreshToken(w, r, tokenHash)
+		return
+	}
+	if accountRestricted(user.Status, user.SuspendedUntil) {
+		respondAccountRestricted(w, user.Status, user.SuspendedUntil)
+		return
+	}
+
+	newRefreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not create refresh token")
+		return
+	}
+	newTokenHash := auth.HashRefreshToken(newRefreshToken)
+
+	_, err = cfg.db.RotateRefreshToken(r.Context(), database.RotateRefreshTokenParams{
+		TokenHash:    tokenHash,
+		NewTokenHash: newTokenHash,
+		ExpiresAt:    time.Now().UTC().Add(cfg.refreshTokenTTL),
+		UserAgent:    clientUserAgent(r),
+		IpAddress:    clientIP(r),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// A concurrent refresh rotated the token first.
+		cfg.respondInvalidRefreshToken(w, r, tokenHash)
+		return
+	}
+	if err != nil {
+		log.Printf("Error rotating refresh token: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not rotate refresh token")
 		return
 	}
 
 	accessToken, err := auth.MakeJWT(
 		user.ID,
-		cfg.secret,
-		time.Hour,
+		auth.Role(user.Role),
+		cfg.jwtKeys,
+		cfg.accessTokenTTL,
 	)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Could not validate token"))
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not create access token")
 		return
 	}
 
 	type resp struct {
-		Token string `json:"token"`
+		Token         string `json:"token"`
+		Refresh_token string `json:"refresh_token"`
 	}
-	newResponse := resp{
-		Token: accessToken,
+	respondWithJSON(w, http.StatusOK, resp{
+		Token:         accessToken,
+		Refresh_token: newRefreshToken,
+	})
+}
+
+// respondInvalidRefreshToken writes a 401 for a refresh token that cannot be
+// used. If the token was rotated before, its whole family is revoked first.
+func (cfg *apiConfig) respondInvalidRefreshToken(w http.ResponseWriter, r *http.Request, tokenHash string) {
+	if cfg.revokeReusedTokenFamily(r.Context(), tokenHash) {
+		respondWithError(w, http.StatusUnauthorized, errCodeRefreshTokenReused, "Refresh token has already been used; log in again")
+		return
 	}
-	dat, err := json.Marshal(newResponse)
+	respondWithError(w, http.StatusUnauthorized, errCodeInvalidToken, "Invalid refresh token")
+}
+
+// revokeReusedTokenFamily reports whether the token hashing to tokenHash has
+// already been rotated and, if so, revokes every token in the same family.
+func (cfg *apiConfig) revokeReusedTokenFamily(ctx context.Context, tokenHash string) bool {
+	token, err := cfg.db.SelectRefreshToken(ctx, tokenHash)
+	if err != nil || !token.ReplacedBy.Valid {
+		return false
+	}
+	revoked, err := cfg.db.RevokeTokenFamily(ctx, token.FamilyID)
 	if err != nil {
-		return
+		log.Printf("Error revoking refresh token family %s: %s", token.FamilyID, err)
+	} else {
+		log.Printf("Refresh token reuse by user %s; revoked %d tokens in family %s", token.UserID, revoked, token.FamilyID)
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(dat)
+	return true
 }
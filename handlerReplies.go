@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/Lockenrocky/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultThreadDepth = 10
+	maxThreadDepth     = 50
+	// threadRepliesPerChirp and maxThreadChirps bound how much of a busy
+	// thread one request loads; the rest is paged through /replies.
+	threadRepliesPerChirp = 20
+	maxThreadChirps       = 500
+)
+
+func (cfg *apiConfig) handlerGetReplies(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Chirp not found")
+		return
+	}
+
+	page, ok := parsePageParams(w, r)
+	if !ok {
+		return
+	}
+
+	_, err = cfg.db.SelectChirp(r.Context(), id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Chirp not found")
+		return
+	}
+
+	replies, err := cfg.db.SelectRepliesPage(r.Context(), database.SelectRepliesPageParams{
+		ParentID:       uuid.NullUUID{UUID: id, Valid: true},
+		AfterCreatedAt: page.cursorTime,
+		AfterID:        page.cursorID,
+		PageSize:       int32(page.limit + 1),
+	})
+	if err != nil {
+		log.Printf("Error selecting replies: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get replies")
+		return
+	}
+
+	replies, nextCursor := pageChirps(replies, page.limit)
+	cfg.respondWithChirpPage(w, r, newChirpPage(replies, nextCursor))
+}
+
+// handlerGetThread returns the conversation the chirp belongs to, starting
+// from its root, down to at most `depth` levels of replies. Busy threads are
+// cut short; chirps whose replies were not all included have more_replies
+// set, and clients page through the rest with the replies listing.
+func (cfg *apiConfig) handlerGetThread(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Chirp not found")
+		return
+	}
+
+	depth := defaultThreadDepth
+	if d := r.URL.Query().Get("depth"); d != "" {
+		depth, err = strconv.Atoi(d)
+		if err != nil || depth < 0 {
+			respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "depth must be a non-negative integer")
+			return
+		}
+		depth = min(depth, maxThreadDepth)
+	}
+
+	rootID, err := cfg.db.SelectThreadRoot(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Chirp not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error finding thread root: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get thread")
+		return
+	}
+
+	rows, err := cfg.db.SelectThread(r.Context(), database.SelectThreadParams{
+		RootID:          rootID,
+		RepliesPerChirp: threadRepliesPerChirp,
+		MaxDepth:        int32(depth),
+		MaxChirps:       maxThreadChirps,
+	})
+	if err != nil {
+		log.Printf("Error selecting thread: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get thread")
+		return
+	}
+
+	root := buildThread(rows)
+	if root == nil {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Chirp not found")
+		return
+	}
+	if err := cfg.annotateChirps(r, root.chirps(nil)); err != nil {
+		log.Printf("Error annotating chirps: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get thread")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, root)
+}
+
+type threadNode struct {
+	resp
+	Replies     []*threadNode `json:"replies"`
+	MoreReplies bool          `json:"more_replies,omitempty"`
+}
+
+// buildThread assembles rows into a tree, replies oldest first. A chirp with
+// fewer replies in rows than its ReplyCount is flagged with MoreReplies.
+func buildThread(rows []database.SelectThreadRow) *threadNode {
+	// SelectThread returns rows level by level but does not sort them.
+	rows = slices.Clone(rows)
+	slices.SortFunc(rows, func(a, b database.SelectThreadRow) int {
+		return cmp.Or(
+			cmp.Compare(a.Depth, b.Depth),
+			a.CreatedAt.Compare(b.CreatedAt),
+			bytes.Compare(a.ID[:], b.ID[:]),
+		)
+	})
+
+	var root *threadNode
+	nodes := make(map[uuid.UUID]*threadNode, len(rows))
+	replyCounts := make(map[uuid.UUID]int64, len(rows))
+
+	for _, row := range rows {
+		chirp := database.Chirp{
+			ID:        row.ID,
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+			Body:      row.Body,
+			UserID:    row.UserID,
+			InReplyTo: row.InReplyTo,
+			LikeCount: row.LikeCount,
+		}
+		node := &threadNode{resp: chirpToResp(chirp), Replies: []*threadNode{}}
+
+		if row.Depth == 0 {
+			root = node
+		} else {
+			parent, ok := nodes[row.InReplyTo.UUID]
+			if !ok {
+				continue
+			}
+			parent.Replies = append(parent.Replies, node)
+		}
+		nodes[row.ID] = node
+		replyCounts[row.ID] = row.ReplyCount
+	}
+
+	for id, node := range nodes {
+		node.MoreReplies = int64(len(node.Replies)) < replyCounts[id]
+	}
+	return root
+}
+
+// chirps appends every chirp in the subtree to dst.
+func (n *threadNode) chirps(dst []*resp) []*resp {
+	dst = append(dst, &n.resp)
+	for _, reply := range n.Replies {
+		dst = reply.chirps(dst)
+	}
+	return dst
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Lockenrocky/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestBuildThread(t *testing.T) {
+	now := time.Now()
+	root := uuid.New()
+	a, b, a1, a1x := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	reply := func(id, parent uuid.UUID, depth int32, age time.Duration, replies int64) database.SelectThreadRow {
+		return database.SelectThreadRow{
+			ID:         id,
+			CreatedAt:  now.Add(-age),
+			InReplyTo:  uuid.NullUUID{UUID: parent, Valid: true},
+			Depth:      depth,
+			ReplyCount: replies,
+		}
+	}
+	rootRow := database.SelectThreadRow{ID: root, CreatedAt: now.Add(-time.Hour), ReplyCount: 2}
+
+	t.Run("Full thread", func(t *testing.T) {
+		// Rows arrive level by level but otherwise unsorted.
+		rows := []database.SelectThreadRow{
+			rootRow,
+			reply(b, root, 1, time.Minute, 0),
+			reply(a, root, 1, 2*time.Minute, 1),
+			reply(a1, a, 2, 0, 1),
+			reply(a1x, a1, 3, 0, 0),
+		}
+		tree := buildThread(rows)
+		if tree == nil || tree.ID != root {
+			t.Fatalf("root = %v, want %v", tree, root)
+		}
+		if len(tree.Replies) != 2 || tree.Replies[0].ID != a || tree.Replies[1].ID != b {
+			t.Fatalf("root replies = %v, want [a b], oldest first", tree.Replies)
+		}
+		deepest := tree.Replies[0].Replies[0].Replies
+		if len(deepest) != 1 || deepest[0].ID != a1x {
+			t.Errorf("depth 3 replies = %v, want [a1x]", deepest)
+		}
+		for _, n := range []*threadNode{tree, tree.Replies[0], tree.Replies[1], deepest[0]} {
+			if n.MoreReplies {
+				t.Errorf("node %s has all its replies but sets MoreReplies", n.ID)
+			}
+		}
+	})
+
+	t.Run("Cut by depth", func(t *testing.T) {
+		rows := []database.SelectThreadRow{
+			rootRow,
+			reply(a, root, 1, 2*time.Minute, 1),
+			reply(b, root, 1, time.Minute, 0),
+			reply(a1, a, 2, 0, 1),
+		}
+		a1Node := buildThread(rows).Replies[0].Replies[0]
+		if !a1Node.MoreReplies {
+			t.Error("node at max depth with replies should set MoreReplies")
+		}
+	})
+
+	t.Run("Cut by breadth", func(t *testing.T) {
+		busy := rootRow
+		busy.ReplyCount = 1000
+		rows := []database.SelectThreadRow{
+			busy,
+			reply(a, root, 1, 2*time.Minute, 0),
+			reply(b, root, 1, time.Minute, 0),
+		}
+		tree := buildThread(rows)
+		if !tree.MoreReplies || len(tree.Replies) != 2 {
+			t.Errorf("root = %d replies, MoreReplies %v; want 2 and true", len(tree.Replies), tree.MoreReplies)
+		}
+		if tree.Replies[0].MoreReplies {
+			t.Error("leaf without replies should not set MoreReplies")
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		if tree := buildThread(nil); tree != nil {
+			t.Errorf("buildThread(nil) = %v, want nil", tree)
+		}
+	})
+}
@@ -0,0 +1,518 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Lockenrocky/chirpy/internal/auth"
+	"github.com/Lockenrocky/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	reportStatusOpen      = "open"
+	reportStatusActioned  = "actioned"
+	reportStatusDismissed = "dismissed"
+
+	reportActionDismiss = "dismiss"
+	reportActionHide    = "hide_chirp"
+	reportActionSuspend = "suspend_user"
+	reportActionReopen  = "reopen"
+
+	maxReportDetails = 1000
+	maxSuspension    = 365 * 24 * time.Hour
+)
+
+var reportReasons = []string{"spam", "harassment", "hate", "violence", "sexual_content", "misinformation", "other"}
+
+var reportStatuses = []string{reportStatusOpen, reportStatusActioned, reportStatusDismissed}
+
+// reportTransitions lists, for each moderator action, the statuses a report
+// may be in beforehand and the status it is left in. Hiding and suspending
+// can both be applied to the same report.
+var reportTransitions = map[string]struct {
+	from []string
+	to   string
+}{
+	reportActionDismiss: {[]string{reportStatusOpen}, reportStatusDismissed},
+	reportActionHide:    {[]string{reportStatusOpen, reportStatusActioned}, reportStatusActioned},
+	reportActionSuspend: {[]string{reportStatusOpen, reportStatusActioned}, reportStatusActioned},
+	reportActionReopen:  {[]string{reportStatusActioned, reportStatusDismissed}, reportStatusOpen},
+}
+
+type reportResp struct {
+	ID               uuid.UUID  `json:"id"`
+	Reporter_id      uuid.UUID  `json:"reporter_id"`
+	Chirp_id         *uuid.UUID `json:"chirp_id"`
+	Chirp_body       *string    `json:"chirp_body"`
+	Reported_user_id uuid.UUID  `json:"reported_user_id"`
+	Reason           string     `json:"reason"`
+	Details          string     `json:"details"`
+	Status           string     `json:"status"`
+	Created_at       time.Time  `json:"created_at"`
+	Updated_at       time.Time  `json:"updated_at"`
+	Resolved_at      *time.Time `json:"resolved_at"`
+	Resolved_by      *uuid.UUID `json:"resolved_by"`
+}
+
+func reportToResp(report database.Report) reportResp {
+	out := reportResp{
+		ID:               report.ID,
+		Reporter_id:      report.ReporterID,
+		Reported_user_id: report.ReportedUserID,
+		Reason:           report.Reason,
+		Details:          report.Details,
+		Status:           report.Status,
+		Created_at:       report.CreatedAt,
+		Updated_at:       report.UpdatedAt,
+	}
+	if report.ChirpID.Valid {
+		out.Chirp_id = &report.ChirpID.UUID
+	}
+	if report.ChirpBody.Valid {
+		out.Chirp_body = &report.ChirpBody.String
+	}
+	if report.ResolvedAt.Valid {
+		out.Resolved_at = &report.ResolvedAt.Time
+	}
+	if report.ResolvedBy.Valid {
+		out.Resolved_by = &report.ResolvedBy.UUID
+	}
+	return out
+}
+
+type reportPage struct {
+	Reports    []reportResp `json:"reports"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+type moderationActionResp struct {
+	ID           uuid.UUID  `json:"id"`
+	Report_id    *uuid.UUID `json:"report_id"`
+	Moderator_id *uuid.UUID `json:"moderator_id"`
+	Action       string     `json:"action"`
+	Chirp_id     *uuid.UUID `json:"chirp_id"`
+	User_id      *uuid.UUID `json:"user_id"`
+	Note         string     `json:"note"`
+	Created_at   time.Time  `json:"created_at"`
+}
+
+func moderationActionToResp(a database.ModerationAction) moderationActionResp {
+	out := moderationActionResp{ID: a.ID, Action: a.Action, Note: a.Note, Created_at: a.CreatedAt}
+	for _, f := range []struct {
+		src uuid.NullUUID
+		dst **uuid.UUID
+	}{
+		{a.ReportID, &out.Report_id},
+		{a.ModeratorID, &out.Moderator_id},
+		{a.ChirpID, &out.Chirp_id},
+		{a.UserID, &out.User_id},
+	} {
+		if f.src.Valid {
+			id := f.src.UUID
+			*f.dst = &id
+		}
+	}
+	return out
+}
+
+type moderationActionPage struct {
+	Actions    []moderationActionResp `json:"actions"`
+	NextCursor string                 `json:"next_cursor,omitempty"`
+}
+
+// handlerReportChirp answers POST /api/chirps/{chirpID}/reports. The report
+// is against the chirp and its author.
+func (cfg *apiConfig) handlerReportChirp(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Chirp not found")
+		return
+	}
+	chirp, err := cfg.db.SelectChirp(r.Context(), id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Chirp not found")
+		return
+	}
+	if chirp.UserID == userID {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "You cannot report your own chirp")
+		return
+	}
+
+	cfg.createReport(w, r, userID, uuid.NullUUID{UUID: chirp.ID, Valid: true}, chirp.UserID)
+}
+
+// handlerReportUser answers POST /api/users/{userID}/reports, for abuse that
+// is not about one chirp.
+func (cfg *apiConfig) handlerReportUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	reportedID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "User not found")
+		return
+	}
+	if reportedID == userID {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "You cannot report yourself")
+		return
+	}
+	if _, err := cfg.db.SelectUserID(r.Context(), reportedID); err != nil {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "User not found")
+		return
+	}
+
+	cfg.createReport(w, r, userID, uuid.NullUUID{}, reportedID)
+}
+
+func (cfg *apiConfig) createReport(w http.ResponseWriter, r *http.Request, reporterID uuid.UUID, chirpID uuid.NullUUID, reportedUserID uuid.UUID) {
+	type parameters struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "Could not decode parameters")
+		return
+	}
+	if !slices.Contains(reportReasons, params.Reason) {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "reason must be one of spam, harassment, hate, violence, sexual_content, misinformation or other")
+		return
+	}
+	if utf8.RuneCountInString(params.Details) > maxReportDetails {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "details must be at most 1000 characters")
+		return
+	}
+
+	report, err := cfg.db.CreateReport(r.Context(), database.CreateReportParams{
+		ReporterID:     reporterID,
+		ChirpID:        chirpID,
+		ReportedUserID: reportedUserID,
+		Reason:         params.Reason,
+		Details:        params.Details,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusConflict, errCodeAlreadyReported, "You already have an open report about this")
+		return
+	}
+	if isForeignKeyViolation(err) {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error creating report: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not create report")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, reportToResp(report))
+}
+
+// handlerGetReports is the moderation queue: reports oldest first, filtered
+// by status, reason, chirp_id and user_id (the reported user).
+func (cfg *apiConfig) handlerGetReports(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	params := database.SelectReportsPageParams{}
+	if s := query.Get("status"); s != "" {
+		if !slices.Contains(reportStatuses, s) {
+			respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "status must be open, actioned or dismissed")
+			return
+		}
+		params.Status = sql.NullString{String: s, Valid: true}
+	}
+	if s := query.Get("reason"); s != "" {
+		if !slices.Contains(reportReasons, s) {
+			respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "Unknown reason")
+			return
+		}
+		params.Reason = sql.NullString{String: s, Valid: true}
+	}
+	var err error
+	if params.ChirpID, err = parseUUIDParam(r, "chirp_id"); err != nil {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidID, "Invalid chirp_id")
+		return
+	}
+	if params.ReportedUserID, err = parseUUIDParam(r, "user_id"); err != nil {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidID, "Invalid user_id")
+		return
+	}
+
+	page, ok := parsePageParams(w, r)
+	if !ok {
+		return
+	}
+	params.AfterCreatedAt = page.cursorTime
+	params.AfterID = page.cursorID
+	params.PageSize = int32(page.limit + 1)
+
+	reports, err := cfg.db.SelectReportsPage(r.Context(), params)
+	if err != nil {
+		log.Printf("Error selecting reports: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get reports")
+		return
+	}
+
+	result := reportPage{Reports: []reportResp{}}
+	if len(reports) > page.limit {
+		reports = reports[:page.limit]
+		last := reports[len(reports)-1]
+		result.NextCursor = encodeCursor(chirpCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	for _, report := range reports {
+		result.Reports = append(result.Reports, reportToResp(report))
+	}
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// handlerGetReport returns a report with the chirp it is about, even if that
+// has been deleted, and what moderators have done with it. Once a deleted
+// chirp is purged only the report's copy of its body is left.
+func (cfg *apiConfig) handlerGetReport(w http.ResponseWriter, r *http.Request) {
+	report, ok := cfg.reportFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	type detail struct {
+		Report  reportResp             `json:"report"`
+		Chirp   *adminChirpResp        `json:"chirp"`
+		Actions []moderationActionResp `json:"actions"`
+	}
+	result := detail{Report: reportToResp(report), Actions: []moderationActionResp{}}
+
+	if report.ChirpID.Valid {
+		chirp, err := cfg.db.SelectChirpIncludingDeleted(r.Context(), report.ChirpID.UUID)
+		if err != nil {
+			log.Printf("Error selecting chirp %s: %s", report.ChirpID.UUID, err)
+			respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get report")
+			return
+		}
+		adminChirp := chirpToAdminResp(chirp)
+		if err := cfg.annotateChirps(r, []*resp{&adminChirp.resp}); err != nil {
+			log.Printf("Error annotating chirps: %s", err)
+			respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get report")
+			return
+		}
+		result.Chirp = &adminChirp
+	}
+
+	actions, err := cfg.db.SelectModerationActionsPage(r.Context(), database.SelectModerationActionsPageParams{
+		ReportID: uuid.NullUUID{UUID: report.ID, Valid: true},
+		PageSize: maxPageSize,
+	})
+	if err != nil {
+		log.Printf("Error selecting moderation actions: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get report")
+		return
+	}
+	for _, a := range actions {
+		result.Actions = append(result.Actions, moderationActionToResp(a))
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// handlerReportAction answers POST /admin/reports/{reportID}/actions. The
+// action is one of dismiss, hide_chirp, suspend_user (with a duration such
+// as "72h") or reopen, and is recorded in the audit trail together with the
+// moderator and an optional note.
+func (cfg *apiConfig) handlerReportAction(w http.ResponseWriter, r *http.Request) {
+	claims, ok := accessClaimsFrom(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errCodeMissingToken, "Could not find access token")
+		return
+	}
+
+	type parameters struct {
+		Action   string `json:"action"`
+		Duration string `json:"duration"`
+		Note     string `json:"note"`
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "Could not decode parameters")
+		return
+	}
+	transition, known := reportTransitions[params.Action]
+	if !known {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "action must be dismiss, hide_chirp, suspend_user or reopen")
+		return
+	}
+	if utf8.RuneCountInString(params.Note) > maxReportDetails {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "note must be at most 1000 characters")
+		return
+	}
+
+	var suspendUntil sql.NullTime
+	if params.Action == reportActionSuspend {
+		d, err := time.ParseDuration(params.Duration)
+		if err != nil || d <= 0 || d > maxSuspension {
+			respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "duration must be a positive duration of at most "+maxSuspension.String())
+			return
+		}
+		suspendUntil = sql.NullTime{Time: time.Now().UTC().Add(d), Valid: true}
+	}
+
+	report, ok := cfg.reportFromPath(w, r)
+	if !ok {
+		return
+	}
+	if params.Action == reportActionHide && !report.ChirpID.Valid {
+		msg := "The report is not about a chirp"
+		if report.ChirpBody.Valid {
+			msg = "The reported chirp has been purged"
+		}
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, msg)
+		return
+	}
+	if !slices.Contains(transition.from, report.Status) {
+		respondWithError(w, http.StatusConflict, errCodeInvalidTransition, "Cannot "+params.Action+" a report that is "+report.Status)
+		return
+	}
+
+	if params.Action == reportActionSuspend {
+		role, err := cfg.db.SelectUserRole(r.Context(), report.ReportedUserID)
+		if err != nil {
+			log.Printf("Error selecting role of user %s: %s", report.ReportedUserID, err)
+			respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not update report")
+			return
+		}
+		if !slices.Contains(suspendableRoles(claims.Role), role) {
+			respondWithError(w, http.StatusForbidden, errCodeForbidden, "You cannot suspend a user whose role is equal to or above yours")
+			return
+		}
+	}
+
+	updated, err := cfg.db.ApplyReportAction(r.Context(), database.ApplyReportActionParams{
+		ID:               report.ID,
+		FromStatuses:     transition.from,
+		Status:           transition.to,
+		ModeratorID:      claims.UserID,
+		Action:           params.Action,
+		Note:             params.Note,
+		HideChirp:        params.Action == reportActionHide,
+		SuspendUntil:     suspendUntil,
+		SuspendableRoles: suspendableRoles(claims.Role),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// Another moderator got there first, or the user's role was raised
+		// since it was checked.
+		respondWithError(w, http.StatusConflict, errCodeInvalidTransition, "The report has changed; reload it and try again")
+		return
+	}
+	if err != nil {
+		log.Printf("Error applying %s to report %s: %s", params.Action, report.ID, err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not update report")
+		return
+	}
+
+	if params.Action == reportActionHide {
+		cfg.publishChirpEvent(chirpEventDeleted, updated.ReportedUserID, struct {
+			ID      uuid.UUID `json:"id"`
+			User_id uuid.UUID `json:"user_id"`
+		}{updated.ChirpID.UUID, updated.ReportedUserID})
+	}
+
+	respondWithJSON(w, http.StatusOK, reportToResp(updated))
+}
+
+// suspendableRoles lists the roles a moderator with role may suspend: those
+// strictly below their own. Changing the status of anyone else is left to
+// /admin/users/{userID}/status.
+func suspendableRoles(role auth.Role) []string {
+	var roles []string
+	for _, target := range []auth.Role{auth.RoleUser, auth.RoleModerator, auth.RoleAdmin} {
+		if !target.Includes(role) {
+			roles = append(roles, string(target))
+		}
+	}
+	return roles
+}
+
+// handlerGetModerationActions returns the audit trail, newest first,
+// optionally narrowed to one report_id or moderator_id.
+func (cfg *apiConfig) handlerGetModerationActions(w http.ResponseWriter, r *http.Request) {
+	reportID, err := parseUUIDParam(r, "report_id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidID, "Invalid report_id")
+		return
+	}
+	moderatorID, err := parseUUIDParam(r, "moderator_id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidID, "Invalid moderator_id")
+		return
+	}
+
+	page, ok := parsePageParams(w, r)
+	if !ok {
+		return
+	}
+
+	actions, err := cfg.db.SelectModerationActionsPage(r.Context(), database.SelectModerationActionsPageParams{
+		ReportID:        reportID,
+		ModeratorID:     moderatorID,
+		BeforeCreatedAt: page.cursorTime,
+		BeforeID:        page.cursorID,
+		PageSize:        int32(page.limit + 1),
+	})
+	if err != nil {
+		log.Printf("Error selecting moderation actions: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get moderation actions")
+		return
+	}
+
+	result := moderationActionPage{Actions: []moderationActionResp{}}
+	if len(actions) > page.limit {
+		actions = actions[:page.limit]
+		last := actions[len(actions)-1]
+		result.NextCursor = encodeCursor(chirpCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	for _, a := range actions {
+		result.Actions = append(result.Actions, moderationActionToResp(a))
+	}
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+func (cfg *apiConfig) reportFromPath(w http.ResponseWriter, r *http.Request) (database.Report, bool) {
+	id, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Report not found")
+		return database.Report{}, false
+	}
+	report, err := cfg.db.SelectReport(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Report not found")
+		return database.Report{}, false
+	}
+	if err != nil {
+		log.Printf("Error selecting report %s: %s", id, err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get report")
+		return database.Report{}, false
+	}
+	return report, true
+}
+
+func parseUUIDParam(r *http.Request, name string) (uuid.NullUUID, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return uuid.NullUUID{}, nil
+	}
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+	return uuid.NullUUID{UUID: id, Valid: true}, nil
+}
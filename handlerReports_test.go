@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lockenrocky/chirpy/internal/auth"
+	"github.com/Lockenrocky/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestSuspendableRoles(t *testing.T) {
+	tests := []struct {
+		role auth.Role
+		want []string
+	}{
+		{auth.RoleUser, nil},
+		{auth.RoleModerator, []string{"user"}},
+		{auth.RoleAdmin, []string{"user", "moderator"}},
+	}
+	for _, tt := range tests {
+		if got := suspendableRoles(tt.role); !slices.Equal(got, tt.want) {
+			t.Errorf("suspendableRoles(%q) = %q, want %q", tt.role, got, tt.want)
+		}
+	}
+}
+
+// seedUserReport adds a user with role and an open report about them, and
+// returns the report's ID.
+func seedUserReport(t *testing.T, conn *sql.DB, role auth.Role) uuid.UUID {
+	t.Helper()
+	reporter, reported, report := uuid.New(), uuid.New(), uuid.New()
+	steps := []struct {
+		query string
+		args  []any
+	}{
+		{`INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+		  VALUES ($1, NOW(), NOW(), $1::text || '@example.com', 'unused')`, []any{reporter}},
+		{`INSERT INTO users (id, created_at, updated_at, email, hashed_password, role)
+		  VALUES ($1, NOW(), NOW(), $1::text || '@example.com', 'unused', $2)`, []any{reported, string(role)}},
+		{`INSERT INTO reports (id, reporter_id, reported_user_id, reason, created_at, updated_at)
+		  VALUES ($1, $2, $3, 'spam', NOW(), NOW())`, []any{report, reporter, reported}},
+	}
+	for _, step := range steps {
+		if _, err := conn.Exec(step.query, step.args...); err != nil {
+			t.Fatalf("seeding report: %v\n%s", err, step.query)
+		}
+	}
+	return report
+}
+
+func TestReportActionSuspendByRole(t *testing.T) {
+	conn := openTestDB(t)
+	cfg := newTestConfig(t)
+	cfg.db = database.New(conn)
+	cfg.dbConn = conn
+	mux := cfg.routes(".")
+
+	moderatorID := uuid.New()
+	if _, err := conn.Exec(`INSERT INTO users (id, created_at, updated_at, email, hashed_password, role)
+		VALUES ($1, NOW(), NOW(), 'moderator@example.com', 'unused', 'moderator')`, moderatorID); err != nil {
+		t.Fatal(err)
+	}
+	bearer := testBearer(t, moderatorID, auth.RoleModerator)
+
+	tests := []struct {
+		target     auth.Role
+		wantStatus int
+	}{
+		{auth.RoleUser, 200},
+		{auth.RoleModerator, 403},
+		{auth.RoleAdmin, 403},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.target), func(t *testing.T) {
+			report := seedUserReport(t, conn, tt.target)
+			req := httptest.NewRequest("POST", "/admin/reports/"+report.String()+"/actions",
+				strings.NewReader(`{"action":"suspend_user","duration":"1h"}`))
+			req.Header.Set("Authorization", bearer)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != 200 {
+				assertErrorEnvelope(t, rec, tt.wantStatus, errCodeForbidden)
+			}
+
+			var status string
+			var suspended sql.NullTime
+			if err := conn.QueryRow(`SELECT reports.status, users.suspended_until
+				FROM reports JOIN users ON users.id = reports.reported_user_id
+				WHERE reports.id = $1`, report).Scan(&status, &suspended); err != nil {
+				t.Fatal(err)
+			}
+			wantReport, wantSuspended := reportStatusOpen, false
+			if tt.wantStatus == 200 {
+				wantReport, wantSuspended = reportStatusActioned, true
+			}
+			if status != wantReport || suspended.Valid != wantSuspended {
+				t.Errorf("report status = %q, suspended = %v; want %q, %v", status, suspended.Valid, wantReport, wantSuspended)
+			}
+		})
+	}
+
+	// The role check in the handler can be raced by a promotion, so the
+	// query has to refuse on its own as well.
+	t.Run("promoted after the check", func(t *testing.T) {
+		report := seedUserReport(t, conn, auth.RoleAdmin)
+		_, err := cfg.db.ApplyReportAction(context.Background(), database.ApplyReportActionParams{
+			ID:               report,
+			FromStatuses:     []string{reportStatusOpen},
+			Status:           reportStatusActioned,
+			ModeratorID:      moderatorID,
+			Action:           reportActionSuspend,
+			SuspendUntil:     sql.NullTime{Time: time.Now().UTC().Add(time.Hour), Valid: true},
+			SuspendableRoles: suspendableRoles(auth.RoleModerator),
+		})
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("ApplyReportAction() error = %v, want sql.ErrNoRows", err)
+		}
+		var status string
+		if err := conn.QueryRow(`SELECT status FROM reports WHERE id = $1`, report).Scan(&status); err != nil {
+			t.Fatal(err)
+		}
+		if status != reportStatusOpen {
+			t.Errorf("report status = %q, want %q", status, reportStatusOpen)
+		}
+	})
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 
 	"github.com/Lockenrocky/chirpy/internal/auth"
@@ -9,15 +11,14 @@ import (
 func (cfg *apiConfig) handlerRevoke(w http.ResponseWriter, r *http.Request) {
 	refreshToken, err := auth.GetBearerToken(r.Header)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Could not find token"))
+		respondWithError(w, http.StatusUnauthorized, errCodeMissingToken, "Could not find token")
 		return
 	}
 
-	_, err = cfg.db.RevokeToken(r.Context(), refreshToken)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("Could not revoke Token!"))
+	// A token that is unknown or already revoked has nothing left to revoke.
+	_, err = cfg.db.RevokeToken(r.Context(), auth.HashRefreshToken(refreshToken))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not revoke token")
 		return
 	}
 
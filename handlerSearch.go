@@ -0,0 +1,172 @@
+package main
+
+import (
+	"database/sql"
+	"html"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Lockenrocky/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Search snippets come back from Postgres with matches wrapped in these
+// private-use runes, which are swapped for <mark> tags after escaping.
+const (
+	highlightStart = "\uE000"
+	highlightStop  = "\uE001"
+)
+
+type searchResult struct {
+	resp
+	Rank    float32 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+type searchPage struct {
+	Results    []searchResult `json:"results"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// handlerSearchChirps answers GET /api/chirps/search. Results are ordered by
+// relevance, so pages are addressed by offset rather than by keyset.
+func (cfg *apiConfig) handlerSearchChirps(w http.ResponseWriter, r *http.Request) {
+	query := tsQuery(r.URL.Query().Get("q"))
+	if query == "" {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "q must contain at least one word")
+		return
+	}
+
+	limit, err := parsePageSize(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "limit must be a positive integer")
+		return
+	}
+
+	offset := 0
+	if c := r.URL.Query().Get("cursor"); c != "" {
+		offset, err = decodeOffsetCursor(c)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, errCodeInvalidCursor, "Invalid cursor")
+			return
+		}
+	}
+
+	params := database.SearchChirpsParams{
+		Query:      query,
+		PageSize:   int32(limit + 1),
+		PageOffset: int32(offset),
+	}
+
+	if s := r.URL.Query().Get("author_id"); s != "" {
+		authorID, err := uuid.Parse(s)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, errCodeInvalidID, "Invalid author_id")
+			return
+		}
+		params.AuthorID = uuid.NullUUID{UUID: authorID, Valid: true}
+	}
+
+	if params.Since, err = parseTimeParam(r, "since"); err != nil {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "since must be an RFC 3339 timestamp")
+		return
+	}
+	if params.Until, err = parseTimeParam(r, "until"); err != nil {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "until must be an RFC 3339 timestamp")
+		return
+	}
+
+	rows, err := cfg.db.SearchChirps(r.Context(), params)
+	if err != nil {
+		log.Printf("Error searching chirps: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not search chirps")
+		return
+	}
+
+	page := searchPage{Results: []searchResult{}}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		page.NextCursor = encodeOffsetCursor(offset + limit)
+	}
+	for _, row := range rows {
+		page.Results = append(page.Results, searchResult{
+			resp: chirpToResp(database.Chirp{
+				ID:        row.ID,
+				CreatedAt: row.CreatedAt,
+				UpdatedAt: row.UpdatedAt,
+				Body:      row.Body,
+				UserID:    row.UserID,
+				InReplyTo: row.InReplyTo,
+				LikeCount: row.LikeCount,
+			}),
+			Rank:    row.Rank,
+			Snippet: highlightSnippet(row.Snippet),
+		})
+	}
+
+	chirps := make([]*resp, len(page.Results))
+	for i := range page.Results {
+		chirps[i] = &page.Results[i].resp
+	}
+	if err := cfg.annotateChirps(r, chirps); err != nil {
+		log.Printf("Error annotating chirps: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not search chirps")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+func parseTimeParam(r *http.Request, name string) (sql.NullTime, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return sql.NullTime{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}, nil
+}
+
+// tsQuery turns a search box string into to_tsquery syntax. Every word must
+// match, "quoted words" must appear together in that order, and a trailing *
+// makes a word match as a prefix. Anything other than letters and digits is
+// treated as a word break, so the result is always valid tsquery input. It
+// returns "" if q has no words.
+func tsQuery(q string) string {
+	var terms []string
+	for i, part := range strings.Split(q, `"`) {
+		words := tsQueryWords(part)
+		if i%2 == 1 && len(words) > 1 {
+			terms = append(terms, "("+strings.Join(words, " <-> ")+")")
+			continue
+		}
+		terms = append(terms, words...)
+	}
+	return strings.Join(terms, " & ")
+}
+
+func tsQueryWords(s string) []string {
+	var words []string
+	for _, field := range strings.Fields(s) {
+		n := len(words)
+		words = append(words, strings.FieldsFunc(field, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsMark(r)
+		})...)
+		if len(words) > n && strings.HasSuffix(field, "*") {
+			words[len(words)-1] += ":*"
+		}
+	}
+	return words
+}
+
+// highlightSnippet escapes a snippet for HTML and marks up the matches.
+func highlightSnippet(s string) string {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, highlightStart, "<mark>")
+	return strings.ReplaceAll(s, highlightStop, "</mark>")
+}
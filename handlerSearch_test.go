@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestTSQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		q    string
+		want string
+	}{
+		{name: "Words", q: "quick fox", want: "quick & fox"},
+		{name: "Phrase", q: `"quick brown" fox`, want: "(quick <-> brown) & fox"},
+		{name: "Single word phrase", q: `"fox"`, want: "fox"},
+		{name: "Prefix", q: "jump* fox", want: "jump:* & fox"},
+		{name: "Prefix in phrase", q: `"brown fo*"`, want: "(brown <-> fo:*)"},
+		{name: "Unclosed quote", q: `fox "lazy dog`, want: "fox & (lazy <-> dog)"},
+		{name: "Operators stripped", q: "fox & !dog | (cat) 'x'", want: "fox & dog & cat & x"},
+		{name: "Unicode", q: "Größe café", want: "Größe & café"},
+		{name: "No words", q: " !? * ", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tsQuery(tt.q); got != tt.want {
+				t.Errorf("tsQuery(%q) = %q, want %q", tt.q, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	got := highlightSnippet("<b>the " + highlightStart + "fox" + highlightStop + "</b>")
+	want := "&lt;b&gt;the <mark>fox</mark>&lt;/b&gt;"
+	if got != want {
+		t.Errorf("highlightSnippet() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Lockenrocky/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxDeviceName = 100
+	maxUserAgent  = 512
+)
+
+type sessionResp struct {
+	ID           uuid.UUID `json:"id"`
+	Device_name  string    `json:"device_name"`
+	User_agent   string    `json:"user_agent"`
+	Ip_address   string    `json:"ip_address"`
+	Created_at   time.Time `json:"created_at"`
+	Last_used_at time.Time `json:"last_used_at"`
+	Expires_at   time.Time `json:"expires_at"`
+}
+
+// handlerGetSessions lists the caller's sessions: one per login that has
+// not been logged out or expired, however often it has been refreshed since.
+func (cfg *apiConfig) handlerGetSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	sessions, err := cfg.db.SelectSessions(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get sessions")
+		return
+	}
+
+	result := []sessionResp{}
+	for _, s := range sessions {
+		result = append(result, sessionResp{
+			ID:           s.FamilyID,
+			Device_name:  s.DeviceName,
+			User_agent:   s.UserAgent,
+			Ip_address:   s.IpAddress,
+			Created_at:   s.StartedAt,
+			Last_used_at: s.LastUsedAt,
+			Expires_at:   s.ExpiresAt,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// handlerRevokeSession logs one of the caller's sessions out. Access tokens
+// already issued to it stay valid until they expire.
+func (cfg *apiConfig) handlerRevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	sessionID, err := uuid.Parse(r.PathValue("sessionID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Session not found")
+		return
+	}
+
+	revoked, err := cfg.db.RevokeSession(r.Context(), database.RevokeSessionParams{FamilyID: sessionID, UserID: userID})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not revoke session")
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, http.StatusNotFound, errCodeNotFound, "Session not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerRevokeAllSessions logs the caller out everywhere, including the
+// session the request came from.
+func (cfg *apiConfig) handlerRevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := cfg.db.RevokeUserTokens(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not revoke sessions")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// clientIP is the address the request came from. Chirpy is not deployed
+// behind a proxy, so X-Forwarded-For is not trusted.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// clientUserAgent is the request's User-Agent, cut short so a client cannot
+// fill the sessions table with it.
+func clientUserAgent(r *http.Request) string {
+	ua := []rune(r.UserAgent())
+	if len(ua) > maxUserAgent {
+		ua = ua[:maxUserAgent]
+	}
+	return string(ua)
+}
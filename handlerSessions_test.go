@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		remoteAddr string
+		want       string
+	}{
+		{"203.0.113.7:51234", "203.0.113.7"},
+		{"[2001:db8::1]:443", "2001:db8::1"},
+		{"no-port", "no-port"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.remoteAddr, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/sessions", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-For", "198.51.100.1")
+			if got := clientIP(req); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientUserAgentTruncates(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/sessions", nil)
+	req.Header.Set("User-Agent", strings.Repeat("é", maxUserAgent+10))
+	if got := []rune(clientUserAgent(req)); len(got) != maxUserAgent {
+		t.Errorf("len(clientUserAgent()) = %d runes, want %d", len(got), maxUserAgent)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Lockenrocky/chirpy/internal/pubsub"
+	"github.com/google/uuid"
+)
+
+const (
+	chirpEventCreated  = "chirp.created"
+	chirpEventDeleted  = "chirp.deleted"
+	chirpEventRestored = "chirp.restored"
+
+	// chirpEventHistory is how many events a reconnecting client can catch
+	// up on; chirpEventBuffer is how far one may fall behind before it is
+	// disconnected.
+	chirpEventHistory = 1024
+	chirpEventBuffer  = 64
+
+	streamHeartbeat    = 15 * time.Second
+	streamWriteTimeout = 10 * time.Second
+)
+
+// chirpEvent is what handlerCreateChirp, handleDeleteChirp and
+// handlerRestoreChirp publish. Data is encoded once at publish time rather
+// than per subscriber.
+type chirpEvent struct {
+	Type     string
+	AuthorID uuid.UUID
+	Data     []byte
+}
+
+func newChirpEvents() *pubsub.Broker[chirpEvent] {
+	return pubsub.New[chirpEvent](chirpEventHistory, chirpEventBuffer)
+}
+
+func (cfg *apiConfig) publishChirpEvent(typ string, authorID uuid.UUID, payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error encoding %s event: %s", typ, err)
+		return
+	}
+	cfg.chirpEvents.Publish(chirpEvent{Type: typ, AuthorID: authorID, Data: data})
+}
+
+// handlerChirpStream answers GET /api/chirps/stream with Server-Sent Events
+// for chirps as they are created and deleted. A client that reconnects with
+// Last-Event-ID is sent what it missed; if that is no longer available it
+// gets a "reset" event and should refetch instead. A client that falls too
+// far behind is disconnected.
+func (cfg *apiConfig) handlerChirpStream(w http.ResponseWriter, r *http.Request) {
+	authorID := uuid.NullUUID{}
+	if s := r.URL.Query().Get("author_id"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, errCodeInvalidID, "Invalid author_id")
+			return
+		}
+		authorID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	var lastID uint64
+	last := r.Header.Get("Last-Event-ID")
+	if last == "" {
+		last = r.URL.Query().Get("last_event_id")
+	}
+	if last != "" {
+		id, err := strconv.ParseUint(last, 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, errCodeInvalidCursor, "Invalid Last-Event-ID")
+			return
+		}
+		lastID = id
+	}
+
+	sub, complete := cfg.chirpEvents.Subscribe(lastID)
+	defer sub.Cancel()
+
+	rc := http.NewResponseController(w)
+	write := func(format string, args ...any) error {
+		// The server's WriteTimeout would end the stream after 30s, so each
+		// write gets its own deadline instead.
+		if err := rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if err := write("retry: 3000\n\n"); err != nil {
+		return
+	}
+	if !complete {
+		if err := write("event: reset\ndata: {}\n\n"); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if err := write(": heartbeat\n\n"); err != nil {
+				return
+			}
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if authorID.Valid && e.Value.AuthorID != authorID.UUID {
+				continue
+			}
+			if err := write("id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Value.Type, e.Value.Data); err != nil {
+				return
+			}
+		}
+	}
+}
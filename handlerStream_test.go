@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// readEvent reads one SSE event, skipping comments and the retry hint, and
+// returns its fields.
+func readEvent(t *testing.T, r *bufio.Reader) map[string]string {
+	t.Helper()
+	fields := map[string]string{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if _, ok := fields["event"]; ok {
+				return fields
+			}
+			fields = map[string]string{}
+			continue
+		}
+		if name, value, ok := strings.Cut(line, ": "); ok && name != "" {
+			fields[name] = value
+		}
+	}
+}
+
+func openStream(t *testing.T, srv *httptest.Server, query, lastEventID string) *bufio.Reader {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", srv.URL+"/api/chirps/stream"+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("GET stream: %v", err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+	return bufio.NewReader(res.Body)
+}
+
+func waitForSubscribers(t *testing.T, cfg *apiConfig, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); cfg.chirpEvents.Len() < n; {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d subscribers, want %d", cfg.chirpEvents.Len(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestChirpStream(t *testing.T) {
+	cfg := newTestConfig(t)
+	srv := httptest.NewServer(cfg.routes("."))
+	// Registered before the streams so they are closed first.
+	t.Cleanup(srv.Close)
+
+	author, other := uuid.New(), uuid.New()
+
+	all := openStream(t, srv, "", "")
+	filtered := openStream(t, srv, "?author_id="+author.String(), "")
+	waitForSubscribers(t, cfg, 2)
+
+	cfg.publishChirpEvent(chirpEventCreated, other, map[string]string{"body": "first"})
+	cfg.publishChirpEvent(chirpEventDeleted, author, map[string]string{"body": "second"})
+
+	if got := readEvent(t, all); got["id"] != "1" || got["event"] != chirpEventCreated || got["data"] != `{"body":"first"}` {
+		t.Errorf("first event = %v", got)
+	}
+	if got := readEvent(t, all); got["id"] != "2" || got["event"] != chirpEventDeleted {
+		t.Errorf("second event = %v", got)
+	}
+	if got := readEvent(t, filtered); got["id"] != "2" {
+		t.Errorf("filtered stream got %v, want only event 2", got)
+	}
+
+	resumed := openStream(t, srv, "", "1")
+	if got := readEvent(t, resumed); got["id"] != "2" {
+		t.Errorf("resumed stream got %v, want event 2", got)
+	}
+
+	stale := openStream(t, srv, "", "99")
+	if got := readEvent(t, stale); got["event"] != "reset" {
+		t.Errorf("stream with unknown Last-Event-ID got %v, want reset", got)
+	}
+}
@@ -30,18 +30,25 @@ func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, r *http.Request)
 	params := parameters{}
 	err := decoder.Decode(&params)
 	if err != nil {
-		log.Fatal("Something went wrong")
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "Could not decode parameters")
 		return
 	}
 
 	hashed_password, err := auth.HashPassword(params.Password)
 	if err != nil {
-		log.Fatal("Error hashing password")
+		log.Printf("Error hashing password: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not hash password")
+		return
 	}
 
 	user, err := cfg.db.CreateUser(r.Context(), database.CreateUserParams{Email: params.Email, HashedPassword: hashed_password})
 	if err != nil {
-		log.Fatalf("Someting went wrong! %s", err)
+		if isUniqueViolation(err) {
+			respondWithError(w, http.StatusConflict, errCodeEmailTaken, "Email is already registered")
+			return
+		}
+		log.Printf("Error creating user: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not create user")
 		return
 	}
 
@@ -53,28 +60,12 @@ func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, r *http.Request)
 		IsChirpyRed: user.IsChirpyRed,
 	}
 
-	dat, err := json.Marshal(created_user)
-	if err != nil {
-		log.Fatalf("Error marshalling JSON %s", err)
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	w.Write(dat)
+	respondWithJSON(w, http.StatusCreated, created_user)
 }
 
 func (cfg *apiConfig) handleUserUpdate(w http.ResponseWriter, r *http.Request) {
-	accessToken, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Could not find access token"))
-		return
-	}
-
-	user_ID, err := auth.ValidateJWT(accessToken, cfg.secret)
-	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Could not find user id"))
+	user_ID, ok := cfg.authenticate(w, r)
+	if !ok {
 		return
 	}
 
@@ -85,10 +76,15 @@ func (cfg *apiConfig) handleUserUpdate(w http.ResponseWriter, r *http.Request) {
 
 	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
-	decoder.Decode(&params)
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "Could not decode parameters")
+		return
+	}
 
 	hashedPassword, err := auth.HashPassword(params.Password)
 	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not hash password")
 		return
 	}
 
@@ -98,8 +94,12 @@ func (cfg *apiConfig) handleUserUpdate(w http.ResponseWriter, r *http.Request) {
 		ID:             user_ID,
 	})
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Could not update user"))
+		if isUniqueViolation(err) {
+			respondWithError(w, http.StatusConflict, errCodeEmailTaken, "Email is already registered")
+			return
+		}
+		log.Printf("Error updating user: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not update user")
 		return
 	}
 
@@ -119,14 +119,5 @@ func (cfg *apiConfig) handleUserUpdate(w http.ResponseWriter, r *http.Request) {
 		Email:      user.Email,
 	}
 
-	dat, err := json.Marshal(updatedUser)
-	if err != nil {
-		w.WriteHeader(400)
-		w.Write([]byte("Error marshaling JSON"))
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(dat)
+	respondWithJSON(w, http.StatusOK, updatedUser)
 }
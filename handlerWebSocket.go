@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Lockenrocky/chirpy/internal/auth"
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/google/uuid"
+)
+
+const (
+	wsReadLimit    = 4096
+	wsWriteTimeout = 10 * time.Second
+	wsPingInterval = 30 * time.Second
+	// wsAccountCheckInterval is how often an open connection re-checks that
+	// its user has not been suspended or banned since it connected.
+	wsAccountCheckInterval = time.Minute
+	wsMaxAuthors           = 100
+
+	wsChannelFeed          = "feed"
+	wsChannelAuthor        = "author"
+	wsChannelNotifications = "notifications"
+)
+
+// wsClientMessage is a message from the client. Type is "subscribe" or
+// "unsubscribe"; Author_id is required for the author channel.
+type wsClientMessage struct {
+	Type      string     `json:"type"`
+	Channel   string     `json:"channel"`
+	Author_id *uuid.UUID `json:"author_id,omitempty"`
+}
+
+// wsServerMessage is a message to the client. Type is "subscribed",
+// "unsubscribed", "event" or "error".
+type wsServerMessage struct {
+	Type      string          `json:"type"`
+	Channel   string          `json:"channel,omitempty"`
+	Author_id *uuid.UUID      `json:"author_id,omitempty"`
+	Event     string          `json:"event,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Message   string          `json:"message,omitempty"`
+}
+
+// handlerWebSocket upgrades GET /api/ws to a WebSocket. The access token is
+// taken from the Authorization header or, since browsers cannot set headers
+// on a WebSocket, from ?access_token=. The connection is closed with 1008
+// when the token expires, and the client is expected to reconnect with a
+// fresh one. A connection whose user is suspended or banned is closed with
+// 1008 within wsAccountCheckInterval.
+func (cfg *apiConfig) handlerWebSocket(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		token = r.URL.Query().Get("access_token")
+	}
+	if token == "" {
+		respondWithError(w, http.StatusUnauthorized, errCodeMissingToken, "Could not find access token")
+		return
+	}
+
+	claims, err := auth.ParseJWT(token, cfg.jwtKeys)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, errCodeInvalidToken, "Invalid access token")
+		return
+	}
+	if !cfg.checkAccount(w, r, claims.UserID) {
+		return
+	}
+
+	// The server's read and write timeouts would otherwise carry over to the
+	// hijacked connection and cut it off.
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return
+	}
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return
+	}
+
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		// Accept has already written the response.
+		return
+	}
+	defer conn.CloseNow()
+	conn.SetReadLimit(wsReadLimit)
+
+	cfg.serveWebSocket(r.Context(), conn, claims)
+}
+
+func (cfg *apiConfig) serveWebSocket(ctx context.Context, conn *websocket.Conn, claims auth.AccessClaims) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	chirps, _ := cfg.chirpEvents.Subscribe(0)
+	defer chirps.Cancel()
+	notifications, _ := cfg.notificationEvents.Subscribe(0)
+	defer notifications.Cancel()
+
+	incoming := make(chan []byte)
+	go func() {
+		defer cancel()
+		for {
+			_, data, err := conn.Read(ctx)
+			if err != nil {
+				return
+			}
+			select {
+			case incoming <- data:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Pings run on their own so that waiting for a pong never holds up the
+	// loop below, which the reader may be blocked on.
+	go func() {
+		ticker := time.NewTicker(wsPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				pingCtx, pingCancel := context.WithTimeout(ctx, wsWriteTimeout)
+				err := conn.Ping(pingCtx)
+				pingCancel()
+				if err != nil {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	send := func(msg wsServerMessage) error {
+		ctx, cancel := context.WithTimeout(ctx, wsWriteTimeout)
+		defer cancel()
+		return wsjson.Write(ctx, conn, msg)
+	}
+
+	expiry := time.NewTimer(time.Until(claims.ExpiresAt))
+	defer expiry.Stop()
+	accountCheck := time.NewTicker(wsAccountCheckInterval)
+	defer accountCheck.Stop()
+
+	var feed, notify bool
+	authors := map[uuid.UUID]bool{}
+
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return
+		case <-expiry.C:
+			conn.Close(websocket.StatusPolicyViolation, "token expired")
+			return
+		case <-accountCheck.C:
+			restriction, restricted, checkErr := cfg.accountRestriction(ctx, claims.UserID)
+			if checkErr != nil {
+				log.Printf("Error checking status of user %s: %s", claims.UserID, checkErr)
+				continue
+			}
+			if restricted {
+				conn.Close(websocket.StatusPolicyViolation, "account "+restriction.Status)
+				return
+			}
+		case data := <-incoming:
+			err = send(handleWSMessage(data, &feed, &notify, authors))
+		case e, ok := <-chirps.C:
+			if !ok {
+				conn.Close(websocket.StatusTryAgainLater, "reconnect")
+				return
+			}
+			channel := wsChannelAuthor
+			if feed {
+				channel = wsChannelFeed
+			} else if !authors[e.Value.AuthorID] {
+				continue
+			}
+			msg := wsServerMessage{Type: "event", Channel: channel, Event: e.Value.Type, Data: e.Value.Data}
+			if channel == wsChannelAuthor {
+				msg.Author_id = &e.Value.AuthorID
+			}
+			err = send(msg)
+		case e, ok := <-notifications.C:
+			if !ok {
+				conn.Close(websocket.StatusTryAgainLater, "reconnect")
+				return
+			}
+			if !notify || e.Value.UserID != claims.UserID {
+				continue
+			}
+			err = send(wsServerMessage{Type: "event", Channel: wsChannelNotifications, Event: e.Value.Type, Data: e.Value.Data})
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// handleWSMessage applies a subscribe or unsubscribe request to the
+// connection's subscriptions and returns the reply.
+func handleWSMessage(data []byte, feed, notify *bool, authors map[uuid.UUID]bool) wsServerMessage {
+	var msg wsClientMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return wsServerMessage{Type: "error", Message: "Could not decode message"}
+	}
+
+	var on bool
+	switch msg.Type {
+	case "subscribe":
+		on = true
+	case "unsubscribe":
+	default:
+		return wsServerMessage{Type: "error", Message: "type must be subscribe or unsubscribe"}
+	}
+
+	reply := wsServerMessage{Type: msg.Type + "d", Channel: msg.Channel}
+	switch msg.Channel {
+	case wsChannelFeed:
+		*feed = on
+	case wsChannelNotifications:
+		*notify = on
+	case wsChannelAuthor:
+		if msg.Author_id == nil {
+			return wsServerMessage{Type: "error", Message: "author_id is required for the author channel"}
+		}
+		if !on {
+			delete(authors, *msg.Author_id)
+		} else if !authors[*msg.Author_id] {
+			if len(authors) >= wsMaxAuthors {
+				return wsServerMessage{Type: "error", Message: "Too many author subscriptions"}
+			}
+			authors[*msg.Author_id] = true
+		}
+		reply.Author_id = msg.Author_id
+	default:
+		return wsServerMessage{Type: "error", Message: "Unknown channel"}
+	}
+	return reply
+}
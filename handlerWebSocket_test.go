@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lockenrocky/chirpy/internal/auth"
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/google/uuid"
+)
+
+func dialWebSocket(t *testing.T, srv *httptest.Server, userID uuid.UUID, ttl time.Duration) (*websocket.Conn, context.Context) {
+	t.Helper()
+	token, err := auth.MakeJWT(userID, auth.RoleUser, testKeys, ttl)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/ws"
+	conn, _, err := websocket.Dial(ctx, url, &websocket.DialOptions{
+		HTTPHeader: http.Header{"Authorization": {"Bearer " + token}},
+	})
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { conn.CloseNow() })
+	return conn, ctx
+}
+
+func exchange(t *testing.T, ctx context.Context, conn *websocket.Conn, msg any) wsServerMessage {
+	t.Helper()
+	if err := wsjson.Write(ctx, conn, msg); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	return readWS(t, ctx, conn)
+}
+
+func readWS(t *testing.T, ctx context.Context, conn *websocket.Conn) wsServerMessage {
+	t.Helper()
+	var reply wsServerMessage
+	if err := wsjson.Read(ctx, conn, &reply); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	return reply
+}
+
+func TestWebSocketSubscriptions(t *testing.T) {
+	cfg := newTestConfig(t)
+	srv := httptest.NewServer(cfg.routes("."))
+	t.Cleanup(srv.Close)
+
+	userID, author, other := uuid.New(), uuid.New(), uuid.New()
+	conn, ctx := dialWebSocket(t, srv, userID, time.Hour)
+
+	if got := exchange(t, ctx, conn, `not an object`); got.Type != "error" {
+		t.Errorf("reply to bad message = %+v, want error", got)
+	}
+	if got := exchange(t, ctx, conn, wsClientMessage{Type: "subscribe", Channel: wsChannelAuthor}); got.Type != "error" {
+		t.Errorf("reply to author without id = %+v, want error", got)
+	}
+	if got := exchange(t, ctx, conn, wsClientMessage{Type: "subscribe", Channel: wsChannelAuthor, Author_id: &author}); got.Type != "subscribed" || *got.Author_id != author {
+		t.Errorf("reply to author subscribe = %+v", got)
+	}
+	if got := exchange(t, ctx, conn, wsClientMessage{Type: "subscribe", Channel: wsChannelNotifications}); got.Type != "subscribed" {
+		t.Errorf("reply to notifications subscribe = %+v", got)
+	}
+
+	cfg.publishChirpEvent(chirpEventCreated, other, map[string]string{"body": "skipped"})
+	cfg.publishChirpEvent(chirpEventCreated, author, map[string]string{"body": "wanted"})
+	if got := readWS(t, ctx, conn); got.Channel != wsChannelAuthor || string(got.Data) != `{"body":"wanted"}` {
+		t.Errorf("event = %+v, want the followed author's chirp", got)
+	}
+
+	cfg.notificationEvents.Publish(notificationEvent{UserID: other, Type: "mention", Data: []byte(`{"for":"other"}`)})
+	cfg.notificationEvents.Publish(notificationEvent{UserID: userID, Type: "mention", Data: []byte(`{"for":"me"}`)})
+	if got := readWS(t, ctx, conn); got.Channel != wsChannelNotifications || string(got.Data) != `{"for":"me"}` {
+		t.Errorf("event = %+v, want own notification", got)
+	}
+
+	if got := exchange(t, ctx, conn, wsClientMessage{Type: "subscribe", Channel: wsChannelFeed}); got.Type != "subscribed" {
+		t.Errorf("reply to feed subscribe = %+v", got)
+	}
+	cfg.publishChirpEvent(chirpEventDeleted, other, map[string]string{"id": "x"})
+	if got := readWS(t, ctx, conn); got.Channel != wsChannelFeed || got.Event != chirpEventDeleted {
+		t.Errorf("event = %+v, want feed deletion", got)
+	}
+}
+
+func TestWebSocketTokenExpiry(t *testing.T) {
+	cfg := newTestConfig(t)
+	srv := httptest.NewServer(cfg.routes("."))
+	t.Cleanup(srv.Close)
+
+	// JWT expiry has one-second resolution.
+	conn, ctx := dialWebSocket(t, srv, uuid.New(), 2*time.Second)
+
+	_, _, err := conn.Read(ctx)
+	var closeErr websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.StatusPolicyViolation {
+		t.Errorf("Read() error = %v, want close with %v", err, websocket.StatusPolicyViolation)
+	}
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -20,6 +21,33 @@ const (
 	TokenTypeAccess TokenType = "chirpy"
 )
 
+// Role is what a user is allowed to do. Each role can do everything the
+// roles before it can.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+var roleRank = map[Role]int{RoleUser: 1, RoleModerator: 2, RoleAdmin: 3}
+
+// ParseRole returns the Role named s, or an error if there is none.
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := roleRank[role]; !ok {
+		return "", fmt.Errorf("unknown role %q", s)
+	}
+	return role, nil
+}
+
+// Includes reports whether r grants everything other does.
+func (r Role) Includes(other Role) bool {
+	rank, ok := roleRank[r]
+	return ok && rank >= roleRank[other]
+}
+
 func HashPassword(password string) (string, error) {
 	hashed_password, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -33,50 +61,92 @@ func CheckPasswordHash(hash, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
 
+// accessTokenClaims is the payload of an access token.
+type accessTokenClaims struct {
+	jwt.RegisteredClaims
+	Role Role `json:"role,omitempty"`
+}
+
+// MakeJWT issues an access token signed with the keyring's current key.
 func MakeJWT(
 	userID uuid.UUID,
-	tokenSecret string,
+	role Role,
+	keys *Keyring,
 	expiresIn time.Duration,
 ) (string, error) {
-	signingKey := []byte(tokenSecret)
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    string(TokenTypeAccess),
-		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
-		Subject:   userID.String(),
+	return keys.sign(accessTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    string(TokenTypeAccess),
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+		Role: role,
 	})
-	return token.SignedString(signingKey)
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	claimsStruct := jwt.RegisteredClaims{}
-	token, err := jwt.ParseWithClaims(
-		tokenString,
-		&claimsStruct,
-		func(token *jwt.Token) (interface{}, error) { return []byte(tokenSecret), nil },
-	)
+// AccessClaims are the parts of a validated access token that callers use.
+// Role is what the user had when the token was issued, so a change of role
+// takes effect at the latest when the token expires.
+type AccessClaims struct {
+	UserID    uuid.UUID
+	Role      Role
+	ExpiresAt time.Time
+}
+
+// ValidateJWT checks an access token against whichever key in keys its kid
+// names and returns the user it was issued to.
+func ValidateJWT(tokenString string, keys *Keyring) (uuid.UUID, error) {
+	claims, err := ParseJWT(tokenString, keys)
 	if err != nil {
 		return uuid.Nil, err
 	}
+	return claims.UserID, nil
+}
+
+// ParseJWT validates an access token like ValidateJWT and also returns the
+// user's role and when the token expires. Tokens issued before roles existed
+// carry none and are treated as RoleUser.
+func ParseJWT(tokenString string, keys *Keyring) (AccessClaims, error) {
+	claimsStruct := accessTokenClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, &claimsStruct, keys.keyFunc)
+	if err != nil {
+		return AccessClaims{}, err
+	}
 
 	userIDString, err := token.Claims.GetSubject()
 	if err != nil {
-		return uuid.Nil, err
+		return AccessClaims{}, err
 	}
 
 	issuer, err := token.Claims.GetIssuer()
 	if err != nil {
-		return uuid.Nil, err
+		return AccessClaims{}, err
 	}
 	if issuer != string(TokenTypeAccess) {
-		return uuid.Nil, errors.New("invalid issuer")
+		return AccessClaims{}, errors.New("invalid issuer")
+	}
+
+	expiresAt, err := token.Claims.GetExpirationTime()
+	if err != nil {
+		return AccessClaims{}, err
+	}
+	if expiresAt == nil {
+		return AccessClaims{}, errors.New("token has no expiry")
 	}
 
 	id, err := uuid.Parse(userIDString)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("invalid user ID: %w", err)
+		return AccessClaims{}, fmt.Errorf("invalid user ID: %w", err)
+	}
+	role := claimsStruct.Role
+	if role == "" {
+		role = RoleUser
 	}
-	return id, nil
+	if _, err := ParseRole(string(role)); err != nil {
+		return AccessClaims{}, err
+	}
+	return AccessClaims{UserID: id, Role: role, ExpiresAt: expiresAt.Time}, nil
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -95,6 +165,14 @@ func MakeRefreshToken() (string, error) {
 	return encodedStr, nil
 }
 
+// HashRefreshToken returns the hex SHA-256 of a refresh token, which is what
+// the database stores and looks tokens up by. Refresh tokens are random, so
+// an unsalted fast hash is enough.
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 func GetAPIKey(headers http.Header) (string, error) {
 	auth := headers.Get("Authorization")
 	if auth == "" {
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
@@ -65,7 +66,7 @@ func CheckPasswordHashTest(t *testing.T) {
 
 func TestValidateJWT(t *testing.T) {
 	userID := uuid.New()
-	validToken, _ := MakeJWT(userID, "secret", time.Hour)
+	validToken, _ := MakeJWT(userID, RoleUser, NewHMACKeyring("secret"), time.Hour)
 
 	tests := []struct {
 		name        string
@@ -99,7 +100,7 @@ func TestValidateJWT(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotUserID, err := ValidateJWT(tt.tokenString, tt.tokenSecret)
+			gotUserID, err := ValidateJWT(tt.tokenString, NewHMACKeyring(tt.tokenSecret))
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ValidateJWT() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -110,3 +111,109 @@ func TestValidateJWT(t *testing.T) {
 		})
 	}
 }
+
+func TestParseJWTExpiry(t *testing.T) {
+	userID := uuid.New()
+	before := time.Now().Add(time.Hour).Truncate(time.Second)
+	token, _ := MakeJWT(userID, RoleUser, NewHMACKeyring("secret"), time.Hour)
+
+	claims, err := ParseJWT(token, NewHMACKeyring("secret"))
+	if err != nil {
+		t.Fatalf("ParseJWT() error = %v", err)
+	}
+	if claims.UserID != userID {
+		t.Errorf("ParseJWT() UserID = %v, want %v", claims.UserID, userID)
+	}
+	if claims.ExpiresAt.Before(before) || claims.ExpiresAt.After(before.Add(2*time.Second)) {
+		t.Errorf("ParseJWT() ExpiresAt = %v, want about %v", claims.ExpiresAt, before)
+	}
+}
+
+func TestParseJWTRole(t *testing.T) {
+	userID := uuid.New()
+	sign := func(claims jwt.Claims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	registered := jwt.RegisteredClaims{
+		Issuer:    string(TokenTypeAccess),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		Subject:   userID.String(),
+	}
+	adminToken, _ := MakeJWT(userID, RoleAdmin, NewHMACKeyring("secret"), time.Hour)
+
+	tests := []struct {
+		name     string
+		token    string
+		wantRole Role
+		wantErr  bool
+	}{
+		{"Role from MakeJWT", adminToken, RoleAdmin, false},
+		{"No role claim", sign(registered), RoleUser, false},
+		{"Unknown role", sign(accessTokenClaims{RegisteredClaims: registered, Role: "owner"}), "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ParseJWT(tt.token, NewHMACKeyring("secret"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseJWT() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if claims.Role != tt.wantRole {
+				t.Errorf("ParseJWT() Role = %q, want %q", claims.Role, tt.wantRole)
+			}
+		})
+	}
+}
+
+func TestRoleIncludes(t *testing.T) {
+	tests := []struct {
+		role, other Role
+		want        bool
+	}{
+		{RoleAdmin, RoleModerator, true},
+		{RoleModerator, RoleModerator, true},
+		{RoleModerator, RoleAdmin, false},
+		{RoleUser, RoleModerator, false},
+		{Role("owner"), RoleUser, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.role.Includes(tt.other); got != tt.want {
+			t.Errorf("%q.Includes(%q) = %v, want %v", tt.role, tt.other, got, tt.want)
+		}
+	}
+}
+
+func TestHashRefreshToken(t *testing.T) {
+	// SHA-256 of "abc" from FIPS 180-2.
+	if got, want := HashRefreshToken("abc"), "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"; got != want {
+		t.Errorf("HashRefreshToken(%q) = %q, want %q", "abc", got, want)
+	}
+
+	token, err := MakeRefreshToken()
+	if err != nil {
+		t.Fatalf("MakeRefreshToken() error = %v", err)
+	}
+	hash := HashRefreshToken(token)
+	if hash == token {
+		t.Errorf("HashRefreshToken() returned the token unchanged")
+	}
+	if len(hash) != 64 {
+		t.Errorf("len(HashRefreshToken()) = %d, want 64", len(hash))
+	}
+	if again := HashRefreshToken(token); again != hash {
+		t.Errorf("HashRefreshToken() not deterministic: %q then %q", hash, again)
+	}
+
+	other, err := MakeRefreshToken()
+	if err != nil {
+		t.Fatalf("MakeRefreshToken() error = %v", err)
+	}
+	if HashRefreshToken(other) == hash {
+		t.Errorf("two refresh tokens hashed to the same value")
+	}
+}
@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// MinRSAKeyBits is the smallest RSA modulus accepted for RS256.
+const MinRSAKeyBits = 2048
+
+// Keyring holds the keys access tokens are signed and verified with. The
+// current key signs new tokens; it and every previous key verify them, so a
+// key can be rotated without logging anyone out: add the new key as current,
+// keep the old one as previous until the last token it signed has expired,
+// then drop it.
+//
+// Tokens carry the ID of the key that signed them in their kid header. Key
+// IDs are RFC 7638 thumbprints of the public key, so the same key file always
+// gets the same ID.
+type Keyring struct {
+	current  *jwtKey
+	previous []*jwtKey
+	keys     map[string]*jwtKey
+}
+
+type jwtKey struct {
+	kid    string
+	method jwt.SigningMethod
+	// sign is nil for a previous key loaded from a public key file.
+	sign   any
+	verify any
+	// jwk is nil for an HMAC secret, which must not be published.
+	jwk *JWK
+}
+
+// NewHMACKeyring returns a keyring that signs and verifies with an HS256
+// shared secret. Its tokens have no kid and it publishes no keys, so only
+// holders of the secret can verify them.
+func NewHMACKeyring(secret string) *Keyring {
+	key := &jwtKey{method: jwt.SigningMethodHS256, sign: []byte(secret), verify: []byte(secret)}
+	return &Keyring{current: key, keys: map[string]*jwtKey{"": key}}
+}
+
+// LoadKeyring reads the signing key from the PEM file at currentPath and the
+// keys it replaced from previousPaths. The current key must be an RSA or
+// Ed25519 private key; previous keys may be private or public.
+func LoadKeyring(currentPath string, previousPaths ...string) (*Keyring, error) {
+	current, err := loadKeyFile(currentPath)
+	if err != nil {
+		return nil, err
+	}
+	if current.sign == nil {
+		return nil, fmt.Errorf("%s: the signing key must be a private key", currentPath)
+	}
+
+	k := &Keyring{current: current, keys: map[string]*jwtKey{current.kid: current}}
+	for _, path := range previousPaths {
+		key, err := loadKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := k.keys[key.kid]; dup {
+			return nil, fmt.Errorf("%s: key %s is loaded twice", path, key.kid)
+		}
+		k.keys[key.kid] = key
+		k.previous = append(k.previous, key)
+	}
+	return k, nil
+}
+
+// AcceptHMAC adds an HS256 shared secret as a previous key, so tokens signed
+// before the switch to a key file stay valid until they expire. It verifies
+// only tokens without a kid, and it is never published.
+func (k *Keyring) AcceptHMAC(secret string) {
+	key := &jwtKey{method: jwt.SigningMethodHS256, verify: []byte(secret)}
+	k.keys[key.kid] = key
+	k.previous = append(k.previous, key)
+}
+
+func loadKeyFile(path string) (*jwtKey, error) {
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading JWT key: %w", err)
+	}
+	key, err := parseKeyPEM(dat)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// parseKeyPEM parses one PEM-encoded RSA or Ed25519 key, private (PKCS #8 or
+// PKCS #1) or public (PKIX or PKCS #1).
+func parseKeyPEM(dat []byte) (*jwtKey, error) {
+	block, _ := pem.Decode(dat)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &jwtKey{}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.sign, parsed = k, &k.PublicKey
+	case ed25519.PrivateKey:
+		key.sign, parsed = k, k.Public()
+	}
+	switch pub := parsed.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < MinRSAKeyBits {
+			return nil, fmt.Errorf("RSA key is %d bits, want at least %d", pub.N.BitLen(), MinRSAKeyBits)
+		}
+		key.method, key.verify = jwt.SigningMethodRS256, pub
+		key.jwk = &JWK{Kty: "RSA", N: b64(pub.N.Bytes()), E: b64(big.NewInt(int64(pub.E)).Bytes())}
+	case ed25519.PublicKey:
+		key.method, key.verify = jwt.SigningMethodEdDSA, pub
+		key.jwk = &JWK{Kty: "OKP", Crv: "Ed25519", X: b64(pub)}
+	default:
+		return nil, fmt.Errorf("unsupported key type %T; use RSA or Ed25519", parsed)
+	}
+
+	key.kid = key.jwk.thumbprint()
+	key.jwk.Kid, key.jwk.Use, key.jwk.Alg = key.kid, "sig", key.method.Alg()
+	return key, nil
+}
+
+// CurrentKeyID is the kid new tokens are signed with; it is empty for an
+// HMAC keyring.
+func (k *Keyring) CurrentKeyID() string {
+	return k.current.kid
+}
+
+func (k *Keyring) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.current.method, claims)
+	if k.current.kid != "" {
+		token.Header["kid"] = k.current.kid
+	}
+	return token.SignedString(k.current.sign)
+}
+
+// keyFunc picks the verification key by the token's kid and refuses a token
+// whose alg does not match that key, so an RSA public key can never be used
+// as an HMAC secret.
+func (k *Keyring) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("key %q does not sign with %s", kid, token.Method.Alg())
+	}
+	return key.verify, nil
+}
+
+// JWK is a public key as published in a JWKS (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public halves of the current and previous keys, in the
+// order they were loaded. HMAC secrets are left out, so an HMAC keyring has
+// nothing to publish.
+func (k *Keyring) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range append([]*jwtKey{k.current}, k.previous...) {
+		if key.jwk != nil {
+			set.Keys = append(set.Keys, *key.jwk)
+		}
+	}
+	return set
+}
+
+// thumbprint is the RFC 7638 JWK thumbprint: the SHA-256 of the required
+// members in lexicographic order.
+func (j *JWK) thumbprint() string {
+	var canonical string
+	switch j.Kty {
+	case "RSA":
+		canonical = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, j.E, j.N)
+	case "OKP":
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":"OKP","x":%q}`, j.Crv, j.X)
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return b64(sum[:])
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,302 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// writePEM writes key as PEM to a file in dir and returns its path. Private
+// keys are written as PKCS #8, public keys as PKIX.
+func writePEM(t *testing.T, dir, name string, key any) string {
+	t.Helper()
+	var block *pem.Block
+	switch key.(type) {
+	case *rsa.PublicKey, ed25519.PublicKey:
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	default:
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newEd25519(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv
+}
+
+func newRSA(t *testing.T, bits int) *rsa.PrivateKey {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv
+}
+
+func TestKeyringSignAndVerify(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		key     any
+		wantAlg string
+	}{
+		{"Ed25519", newEd25519(t), "EdDSA"},
+		{"RSA", newRSA(t, 2048), "RS256"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := LoadKeyring(writePEM(t, dir, tt.name+".pem", tt.key))
+			if err != nil {
+				t.Fatalf("LoadKeyring() error = %v", err)
+			}
+
+			userID := uuid.New()
+			token, err := MakeJWT(userID, RoleModerator, keys, time.Hour)
+			if err != nil {
+				t.Fatalf("MakeJWT() error = %v", err)
+			}
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &jwt.RegisteredClaims{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed.Header["alg"] != tt.wantAlg || parsed.Header["kid"] != keys.CurrentKeyID() {
+				t.Errorf("header = %v, want alg %s and kid %s", parsed.Header, tt.wantAlg, keys.CurrentKeyID())
+			}
+
+			claims, err := ParseJWT(token, keys)
+			if err != nil {
+				t.Fatalf("ParseJWT() error = %v", err)
+			}
+			if claims.UserID != userID || claims.Role != RoleModerator {
+				t.Errorf("ParseJWT() = %+v, want user %s with role moderator", claims, userID)
+			}
+		})
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldKey := newEd25519(t)
+	oldPath := writePEM(t, dir, "old.pem", oldKey)
+	oldPublicPath := writePEM(t, dir, "old.pub.pem", oldKey.Public())
+	newPath := writePEM(t, dir, "new.pem", newRSA(t, 2048))
+
+	before, err := LoadKeyring(oldPath)
+	if err != nil {
+		t.Fatalf("LoadKeyring() error = %v", err)
+	}
+	userID := uuid.New()
+	oldToken, err := MakeJWT(userID, RoleUser, before, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+
+	during, err := LoadKeyring(newPath, oldPublicPath)
+	if err != nil {
+		t.Fatalf("LoadKeyring() error = %v", err)
+	}
+	if during.CurrentKeyID() == before.CurrentKeyID() {
+		t.Fatalf("new key has the same kid as the old one")
+	}
+	if got, err := ValidateJWT(oldToken, during); err != nil || got != userID {
+		t.Errorf("ValidateJWT(old token, rotated keyring) = %v, %v; want %v", got, err, userID)
+	}
+	newToken, err := MakeJWT(userID, RoleUser, during, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+	if _, err := ValidateJWT(newToken, before); err == nil {
+		t.Errorf("ValidateJWT(new token, old keyring) succeeded, want unknown kid")
+	}
+
+	after, err := LoadKeyring(newPath)
+	if err != nil {
+		t.Fatalf("LoadKeyring() error = %v", err)
+	}
+	if _, err := ValidateJWT(oldToken, after); err == nil {
+		t.Errorf("ValidateJWT() accepted a token signed by a dropped key")
+	}
+}
+
+func TestKeyringAcceptHMAC(t *testing.T) {
+	const secret = "0123456789abcdef0123456789abcdef"
+	userID := uuid.New()
+	oldToken, err := MakeJWT(userID, RoleUser, NewHMACKeyring(secret), time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+
+	keys, err := LoadKeyring(writePEM(t, t.TempDir(), "current.pem", newEd25519(t)))
+	if err != nil {
+		t.Fatalf("LoadKeyring() error = %v", err)
+	}
+	if _, err := ValidateJWT(oldToken, keys); err == nil {
+		t.Errorf("ValidateJWT() accepted an HS256 token without the secret")
+	}
+
+	keys.AcceptHMAC(secret)
+	if got, err := ValidateJWT(oldToken, keys); err != nil || got != userID {
+		t.Errorf("ValidateJWT(HS256 token) = %v, %v; want %v", got, err, userID)
+	}
+	forged, err := MakeJWT(userID, RoleAdmin, NewHMACKeyring("some other secret of 32 characters"), time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+	if _, err := ValidateJWT(forged, keys); err == nil {
+		t.Errorf("ValidateJWT() accepted an HS256 token signed with another secret")
+	}
+
+	newToken, err := MakeJWT(userID, RoleUser, keys, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+	parsed, _, err := jwt.NewParser().ParseUnverified(newToken, &jwt.RegisteredClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Header["alg"] != "EdDSA" {
+		t.Errorf("new token alg = %v, want EdDSA", parsed.Header["alg"])
+	}
+	if set := keys.JWKS(); len(set.Keys) != 1 || set.Keys[0].Kid != keys.CurrentKeyID() {
+		t.Errorf("JWKS() = %+v, want only the current key", set)
+	}
+}
+
+func TestKeyringRejectsAlgorithmConfusion(t *testing.T) {
+	dir := t.TempDir()
+	priv := newRSA(t, 2048)
+	keys, err := LoadKeyring(writePEM(t, dir, "key.pem", priv))
+	if err != nil {
+		t.Fatalf("LoadKeyring() error = %v", err)
+	}
+
+	// An attacker who knows the public key signs with it as an HMAC secret
+	// and claims the real kid.
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Issuer:    string(TokenTypeAccess),
+		Subject:   uuid.NewString(),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	})
+	forged.Header["kid"] = keys.CurrentKeyID()
+	token, err := forged.SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateJWT(token, keys); err == nil {
+		t.Errorf("ValidateJWT() accepted an HS256 token for an RS256 key")
+	}
+
+	hmacToken, err := MakeJWT(uuid.New(), RoleUser, NewHMACKeyring("secret"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateJWT(hmacToken, keys); err == nil {
+		t.Errorf("ValidateJWT() accepted a token without a kid")
+	}
+}
+
+func TestLoadKeyringErrors(t *testing.T) {
+	dir := t.TempDir()
+	priv := newEd25519(t)
+	privPath := writePEM(t, dir, "key.pem", priv)
+	notPEM := filepath.Join(dir, "junk.pem")
+	if err := os.WriteFile(notPEM, []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		current  string
+		previous []string
+		wantErr  string
+	}{
+		{"Missing file", filepath.Join(dir, "missing.pem"), nil, "reading JWT key"},
+		{"Not PEM", notPEM, nil, "no PEM data"},
+		{"Public signing key", writePEM(t, dir, "key.pub.pem", priv.Public()), nil, "must be a private key"},
+		{"Short RSA key", writePEM(t, dir, "short.pem", newRSA(t, 1024)), nil, "at least 2048"},
+		{"Duplicate key", privPath, []string{privPath}, "loaded twice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadKeyring(tt.current, tt.previous...)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadKeyring() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyringJWKS(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey := newRSA(t, 2048)
+	keys, err := LoadKeyring(writePEM(t, dir, "current.pem", newEd25519(t)), writePEM(t, dir, "old.pem", &rsaKey.PublicKey))
+	if err != nil {
+		t.Fatalf("LoadKeyring() error = %v", err)
+	}
+
+	set := keys.JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("JWKS() has %d keys, want 2", len(set.Keys))
+	}
+	current, old := set.Keys[0], set.Keys[1]
+	if current.Kid != keys.CurrentKeyID() || current.Kty != "OKP" || current.Crv != "Ed25519" || current.Alg != "EdDSA" || current.X == "" {
+		t.Errorf("current key = %+v", current)
+	}
+	if old.Kty != "RSA" || old.Alg != "RS256" || old.E != "AQAB" || old.N == "" || old.Use != "sig" {
+		t.Errorf("previous key = %+v", old)
+	}
+	if old.Kid != old.thumbprint() {
+		t.Errorf("kid %q is not the JWK thumbprint %q", old.Kid, old.thumbprint())
+	}
+
+	if got := NewHMACKeyring("secret").JWKS(); len(got.Keys) != 0 {
+		t.Errorf("HMAC keyring publishes %d keys, want 0", len(got.Keys))
+	}
+}
+
+func TestJWKThumbprint(t *testing.T) {
+	// The example from RFC 7638, section 3.1.
+	key := JWK{
+		Kty: "RSA",
+		E:   "AQAB",
+		N: "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn6" +
+			"4tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91" +
+			"CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+	}
+	if got, want := key.thumbprint(), "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"; got != want {
+		t.Errorf("thumbprint() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,229 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// MinSecretLength is the shortest SECRET accepted for signing JWTs with
+// HS256. Keys shorter than the hash output weaken the signature.
+const MinSecretLength = 32
+
+type Config struct {
+	DBURL           string        `yaml:"db_url"`
+	Platform        string        `yaml:"platform"`
+	Secret          string        `yaml:"secret"`
+	PolkaKey        string        `yaml:"polka_key"`
+	Addr            string        `yaml:"addr"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	ProfanityFile   string        `yaml:"profanity_file"`
+	MediaDir        string        `yaml:"media_dir"`
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl"`
+	// UndeleteWindow is how long an owner can restore a chirp they deleted.
+	// ChirpRetention is how long deleted chirps are kept before they are
+	// purged for good; it must be at least UndeleteWindow.
+	UndeleteWindow time.Duration `yaml:"undelete_window"`
+	ChirpRetention time.Duration `yaml:"chirp_retention"`
+	// JWTKeyFile is a PEM RSA or Ed25519 private key to sign access tokens
+	// with. JWTPreviousKeyFiles are keys it replaced, kept only to verify
+	// tokens they signed. Without JWTKeyFile tokens are signed with Secret
+	// using HS256; with it, a Secret that is still set only verifies the
+	// tokens it signed before the switch.
+	JWTKeyFile          string   `yaml:"jwt_key_file"`
+	JWTPreviousKeyFiles []string `yaml:"jwt_previous_key_files"`
+}
+
+func defaults() Config {
+	return Config{
+		Addr:            ":8080",
+		MediaDir:        "media",
+		ShutdownTimeout: 15 * time.Second,
+		AccessTokenTTL:  time.Hour,
+		RefreshTokenTTL: 60 * 24 * time.Hour,
+		UndeleteWindow:  24 * time.Hour,
+		ChirpRetention:  30 * 24 * time.Hour,
+	}
+}
+
+// Load builds the configuration from, in increasing order of precedence:
+// built-in defaults, the YAML file at path (if path is not empty), a .env
+// file in the working directory, and the process environment. PORT is the
+// exception: it yields to an addr from the file. On any error the Config as
+// loaded so far is returned together with the error, so callers can still
+// report what was read.
+func Load(path string) (Config, error) {
+	cfg := defaults()
+
+	// PORT must not override an addr the file sets, so note whether it does.
+	var file struct {
+		Addr *string `yaml:"addr"`
+	}
+	if path != "" {
+		dat, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("reading config file: %w", err)
+		}
+		if err := yaml.Unmarshal(dat, &cfg); err != nil {
+			return cfg, fmt.Errorf("parsing config file %s: %w", path, err)
+		}
+		if err := yaml.Unmarshal(dat, &file); err != nil {
+			return cfg, fmt.Errorf("parsing config file %s: %w", path, err)
+		}
+	}
+
+	// A missing .env is fine; it only exists in local development.
+	godotenv.Load()
+
+	if err := cfg.applyEnv(file.Addr != nil); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// applyEnv overrides c with the environment. fileAddr reports whether the
+// config file set addr. A variable that does not parse is reported and the
+// rest are still applied.
+func (c *Config) applyEnv(fileAddr bool) error {
+	vars := []struct {
+		key string
+		dst *string
+	}{
+		{"DB_URL", &c.DBURL},
+		{"PLATFORM", &c.Platform},
+		{"SECRET", &c.Secret},
+		{"POLKA_KEY", &c.PolkaKey},
+		{"ADDR", &c.Addr},
+		{"PROFANITY_FILE", &c.ProfanityFile},
+		{"MEDIA_DIR", &c.MediaDir},
+		{"JWT_KEY_FILE", &c.JWTKeyFile},
+	}
+	for _, v := range vars {
+		if val, ok := os.LookupEnv(v.key); ok {
+			*v.dst = val
+		}
+	}
+
+	// JWT_PREVIOUS_KEY_FILES is a comma-separated list; set it empty to drop
+	// every previous key.
+	if val, ok := os.LookupEnv("JWT_PREVIOUS_KEY_FILES"); ok {
+		c.JWTPreviousKeyFiles = nil
+		for _, path := range strings.Split(val, ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				c.JWTPreviousKeyFiles = append(c.JWTPreviousKeyFiles, path)
+			}
+		}
+	}
+
+	// PORT is what most hosting platforms set; it only fills in for an addr
+	// that neither ADDR nor the config file gives.
+	if port, ok := os.LookupEnv("PORT"); ok {
+		if _, hasAddr := os.LookupEnv("ADDR"); !hasAddr && !fileAddr {
+			c.Addr = ":" + port
+		}
+	}
+
+	durations := []struct {
+		key string
+		dst *time.Duration
+	}{
+		{"SHUTDOWN_TIMEOUT", &c.ShutdownTimeout},
+		{"ACCESS_TOKEN_TTL", &c.AccessTokenTTL},
+		{"REFRESH_TOKEN_TTL", &c.RefreshTokenTTL},
+		{"UNDELETE_WINDOW", &c.UndeleteWindow},
+		{"CHIRP_RETENTION", &c.ChirpRetention},
+	}
+	var errs []error
+	for _, d := range durations {
+		v, ok := os.LookupEnv(d.key)
+		if !ok {
+			continue
+		}
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.key, err))
+			continue
+		}
+		*d.dst = parsed
+	}
+	return errors.Join(errs...)
+}
+
+// Validate reports every problem with the configuration at once so a broken
+// deploy can be fixed in one go.
+func (c Config) Validate() error {
+	var errs []error
+	if c.DBURL == "" {
+		errs = append(errs, errors.New("DB_URL must be set"))
+	}
+	if c.JWTKeyFile == "" {
+		if len(c.Secret) < MinSecretLength {
+			errs = append(errs, fmt.Errorf("SECRET must be at least %d characters unless JWT_KEY_FILE is set", MinSecretLength))
+		}
+		if len(c.JWTPreviousKeyFiles) > 0 {
+			errs = append(errs, errors.New("JWT_PREVIOUS_KEY_FILES requires JWT_KEY_FILE"))
+		}
+	} else if c.Secret != "" && len(c.Secret) < MinSecretLength {
+		errs = append(errs, fmt.Errorf("SECRET must be at least %d characters or unset", MinSecretLength))
+	}
+	if c.Addr == "" {
+		errs = append(errs, errors.New("ADDR must not be empty"))
+	}
+	if c.MediaDir == "" {
+		errs = append(errs, errors.New("MEDIA_DIR must not be empty"))
+	}
+	durations := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"SHUTDOWN_TIMEOUT", c.ShutdownTimeout},
+		{"ACCESS_TOKEN_TTL", c.AccessTokenTTL},
+		{"REFRESH_TOKEN_TTL", c.RefreshTokenTTL},
+		{"UNDELETE_WINDOW", c.UndeleteWindow},
+		{"CHIRP_RETENTION", c.ChirpRetention},
+	}
+	for _, d := range durations {
+		if d.d <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", d.name))
+		}
+	}
+	if c.ChirpRetention < c.UndeleteWindow {
+		errs = append(errs, errors.New("CHIRP_RETENTION must be at least UNDELETE_WINDOW"))
+	}
+	return errors.Join(errs...)
+}
+
+// Redacted returns the configuration as KEY=value lines with secrets masked,
+// suitable for printing or logging.
+func (c Config) Redacted() string {
+	lines := []string{
+		"DB_URL=" + redact(c.DBURL),
+		"PLATFORM=" + c.Platform,
+		"SECRET=" + redact(c.Secret),
+		"POLKA_KEY=" + redact(c.PolkaKey),
+		"ADDR=" + c.Addr,
+		"SHUTDOWN_TIMEOUT=" + c.ShutdownTimeout.String(),
+		"PROFANITY_FILE=" + c.ProfanityFile,
+		"MEDIA_DIR=" + c.MediaDir,
+		"ACCESS_TOKEN_TTL=" + c.AccessTokenTTL.String(),
+		"REFRESH_TOKEN_TTL=" + c.RefreshTokenTTL.String(),
+		"UNDELETE_WINDOW=" + c.UndeleteWindow.String(),
+		"CHIRP_RETENTION=" + c.ChirpRetention.String(),
+		"JWT_KEY_FILE=" + c.JWTKeyFile,
+		"JWT_PREVIOUS_KEY_FILES=" + strings.Join(c.JWTPreviousKeyFiles, ","),
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func redact(s string) string {
+	if s == "" {
+		return ""
+	}
+	return fmt.Sprintf("<redacted, %d chars>", len(s))
+}
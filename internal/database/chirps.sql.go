@@ -7,29 +7,37 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-VALUES (
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to)
+SELECT
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
+WHERE $3::uuid IS NULL OR EXISTS (
+    SELECT 1 FROM chirps AS parent
+    WHERE parent.id = $3::uuid AND parent.deleted_at IS NULL
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, like_count, deleted_at, deleted_by
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body      string
+	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
 }
 
+// Returns no row if in_reply_to names a deleted chirp.
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID, arg.InReplyTo)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,18 +45,31 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.InReplyTo,
+		&i.LikeCount,
+		&i.DeletedAt,
+		&i.DeletedBy,
 	)
 	return i, err
 }
 
-const deleteChirp = `-- name: DeleteChirp :exec
-DELETE FROM chirps
-WHERE id = $1
+const deleteChirp = `-- name: DeleteChirp :execrows
+UPDATE chirps
+SET deleted_at = NOW(), deleted_by = $1
+WHERE id = $2 AND deleted_at IS NULL
 `
 
-func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteChirp, id)
-	return err
+type DeleteChirpParams struct {
+	DeletedBy uuid.NullUUID
+	ID        uuid.UUID
+}
+
+func (q *Queries) DeleteChirp(ctx context.Context, arg DeleteChirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteChirp, arg.DeletedBy, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const deleteChirps = `-- name: DeleteChirps :exec
@@ -60,8 +81,76 @@ func (q *Queries) DeleteChirps(ctx context.Context) error {
 	return err
 }
 
+const purgeDeletedChirps = `-- name: PurgeDeletedChirps :many
+WITH purged AS (
+    DELETE FROM chirps
+    WHERE deleted_at < $1
+    AND NOT EXISTS (SELECT 1 FROM reports WHERE reports.chirp_id = chirps.id AND reports.status = 'open')
+    RETURNING id
+)
+SELECT purged.id, media.file_key, media.thumbnail_key FROM purged
+LEFT JOIN media ON media.chirp_id = purged.id
+`
+
+type PurgeDeletedChirpsRow struct {
+	ID           uuid.UUID
+	FileKey      sql.NullString
+	ThumbnailKey sql.NullString
+}
+
+// Returns one row per purged chirp and attachment so the caller can remove
+// the media files, which the cascade takes out of the media table. Chirps
+// with open reports are kept until a moderator has dealt with them.
+func (q *Queries) PurgeDeletedChirps(ctx context.Context, deletedBefore sql.NullTime) ([]PurgeDeletedChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, purgeDeletedChirps, deletedBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PurgeDeletedChirpsRow
+	for rows.Next() {
+		var i PurgeDeletedChirpsRow
+		if err := rows.Scan(&i.ID, &i.FileKey, &i.ThumbnailKey); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreChirp = `-- name: RestoreChirp :one
+UPDATE chirps
+SET deleted_at = NULL, deleted_by = NULL
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, like_count, deleted_at, deleted_by
+`
+
+func (q *Queries) RestoreChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, restoreChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.LikeCount,
+		&i.DeletedAt,
+		&i.DeletedBy,
+	)
+	return i, err
+}
+
 const selectAllChirps = `-- name: SelectAllChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, like_count, deleted_at, deleted_by FROM chirps
+WHERE deleted_at IS NULL
 ORDER BY created_at
 `
 
@@ -80,6 +169,10 @@ func (q *Queries) SelectAllChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.InReplyTo,
+			&i.LikeCount,
+			&i.DeletedAt,
+			&i.DeletedBy,
 		); err != nil {
 			return nil, err
 		}
@@ -95,8 +188,8 @@ func (q *Queries) SelectAllChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const selectAllChirpsFromAuthor = `-- name: SelectAllChirpsFromAuthor :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-WHERE user_id = $1
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, like_count, deleted_at, deleted_by FROM chirps
+WHERE user_id = $1 AND deleted_at IS NULL
 ORDER BY created_at
 `
 
@@ -115,6 +208,10 @@ func (q *Queries) SelectAllChirpsFromAuthor(ctx context.Context, userID uuid.UUI
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.InReplyTo,
+			&i.LikeCount,
+			&i.DeletedAt,
+			&i.DeletedBy,
 		); err != nil {
 			return nil, err
 		}
@@ -130,8 +227,8 @@ func (q *Queries) SelectAllChirpsFromAuthor(ctx context.Context, userID uuid.UUI
 }
 
 const selectChirp = `-- name: SelectChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-WHERE id = $1
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, like_count, deleted_at, deleted_by FROM chirps
+WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) SelectChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -143,6 +240,521 @@ func (q *Queries) SelectChirp(ctx context.Context, id uuid.UUID) (Chirp, error)
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.InReplyTo,
+		&i.LikeCount,
+		&i.DeletedAt,
+		&i.DeletedBy,
+	)
+	return i, err
+}
+
+const selectChirpIncludingDeleted = `-- name: SelectChirpIncludingDeleted :one
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, like_count, deleted_at, deleted_by FROM chirps
+WHERE id = $1
+`
+
+func (q *Queries) SelectChirpIncludingDeleted(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, selectChirpIncludingDeleted, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.LikeCount,
+		&i.DeletedAt,
+		&i.DeletedBy,
+	)
+	return i, err
+}
+
+const selectChirpsPageAsc = `-- name: SelectChirpsPageAsc :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, like_count, deleted_at, deleted_by FROM chirps
+WHERE deleted_at IS NULL
+AND ($1::uuid IS NULL OR user_id = $1::uuid)
+AND (
+    $2::timestamp IS NULL
+    OR (created_at, id) > ($2::timestamp, $3::uuid)
+)
+ORDER BY created_at ASC, id ASC
+LIMIT $4
+`
+
+type SelectChirpsPageAscParams struct {
+	AuthorID       uuid.NullUUID
+	AfterCreatedAt sql.NullTime
+	AfterID        uuid.NullUUID
+	PageSize       int32
+}
+
+func (q *Queries) SelectChirpsPageAsc(ctx context.Context, arg SelectChirpsPageAscParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, selectChirpsPageAsc,
+		arg.AuthorID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.LikeCount,
+			&i.DeletedAt,
+			&i.DeletedBy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const selectChirpsPageDesc = `-- name: SelectChirpsPageDesc :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, like_count, deleted_at, deleted_by FROM chirps
+WHERE deleted_at IS NULL
+AND ($1::uuid IS NULL OR user_id = $1::uuid)
+AND (
+    $2::timestamp IS NULL
+    OR (created_at, id) < ($2::timestamp, $3::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type SelectChirpsPageDescParams struct {
+	AuthorID        uuid.NullUUID
+	BeforeCreatedAt sql.NullTime
+	BeforeID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) SelectChirpsPageDesc(ctx context.Context, arg SelectChirpsPageDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, selectChirpsPageDesc,
+		arg.AuthorID,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.LikeCount,
+			&i.DeletedAt,
+			&i.DeletedBy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const selectChirpsPageLikes = `-- name: SelectChirpsPageLikes :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, like_count, deleted_at, deleted_by FROM chirps
+WHERE deleted_at IS NULL
+AND ($1::uuid IS NULL OR user_id = $1::uuid)
+AND (
+    $2::int IS NULL
+    OR (like_count, created_at, id) < ($2::int, $3::timestamp, $4::uuid)
+)
+ORDER BY like_count DESC, created_at DESC, id DESC
+LIMIT $5
+`
+
+type SelectChirpsPageLikesParams struct {
+	AuthorID        uuid.NullUUID
+	BeforeLikeCount sql.NullInt32
+	BeforeCreatedAt sql.NullTime
+	BeforeID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) SelectChirpsPageLikes(ctx context.Context, arg SelectChirpsPageLikesParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, selectChirpsPageLikes,
+		arg.AuthorID,
+		arg.BeforeLikeCount,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.LikeCount,
+			&i.DeletedAt,
+			&i.DeletedBy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const selectDeletedChirpsPage = `-- name: SelectDeletedChirpsPage :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, like_count, deleted_at, deleted_by FROM chirps
+WHERE deleted_at IS NOT NULL
+AND (
+    $1::timestamp IS NULL
+    OR (deleted_at, id) < ($1::timestamp, $2::uuid)
+)
+ORDER BY deleted_at DESC, id DESC
+LIMIT $3
+`
+
+type SelectDeletedChirpsPageParams struct {
+	BeforeDeletedAt sql.NullTime
+	BeforeID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) SelectDeletedChirpsPage(ctx context.Context, arg SelectDeletedChirpsPageParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, selectDeletedChirpsPage, arg.BeforeDeletedAt, arg.BeforeID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.LikeCount,
+			&i.DeletedAt,
+			&i.DeletedBy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const selectRepliesPage = `-- name: SelectRepliesPage :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, like_count, deleted_at, deleted_by FROM chirps
+WHERE in_reply_to = $1 AND deleted_at IS NULL
+AND (
+    $2::timestamp IS NULL
+    OR (created_at, id) > ($2::timestamp, $3::uuid)
+)
+ORDER BY created_at ASC, id ASC
+LIMIT $4
+`
+
+type SelectRepliesPageParams struct {
+	ParentID       uuid.NullUUID
+	AfterCreatedAt sql.NullTime
+	AfterID        uuid.NullUUID
+	PageSize       int32
+}
+
+func (q *Queries) SelectRepliesPage(ctx context.Context, arg SelectRepliesPageParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, selectRepliesPage,
+		arg.ParentID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.LikeCount,
+			&i.DeletedAt,
+			&i.DeletedBy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const selectThread = `-- name: SelectThread :many
+WITH RECURSIVE thread AS (
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.like_count, 0 AS depth
+    FROM chirps
+    WHERE chirps.id = $1 AND chirps.deleted_at IS NULL
+    UNION ALL
+    SELECT c.id, c.created_at, c.updated_at, c.body, c.user_id, c.in_reply_to, c.like_count, thread.depth + 1
+    FROM thread
+    CROSS JOIN LATERAL (
+        SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.like_count
+        FROM chirps
+        WHERE chirps.in_reply_to = thread.id AND chirps.deleted_at IS NULL
+        ORDER BY chirps.created_at, chirps.id
+        LIMIT $2::int
+    ) AS c
+    WHERE thread.depth < $3::int
+)
+SELECT thread.id, thread.created_at, thread.updated_at, thread.body, thread.user_id, thread.in_reply_to, thread.like_count, thread.depth,
+    (SELECT count(*) FROM chirps AS r WHERE r.in_reply_to = thread.id AND r.deleted_at IS NULL) AS reply_count
+FROM thread
+LIMIT $4
+`
+
+type SelectThreadParams struct {
+	RootID          uuid.UUID
+	RepliesPerChirp int32
+	MaxDepth        int32
+	MaxChirps       int32
+}
+
+type SelectThreadRow struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Body       string
+	UserID     uuid.UUID
+	InReplyTo  uuid.NullUUID
+	LikeCount  int32
+	Depth      int32
+	ReplyCount int64
+}
+
+// Each chirp contributes at most replies_per_chirp replies, oldest first, and
+// the thread at most max_chirps chirps. There is deliberately no ORDER BY:
+// Postgres evaluates a recursive query level by level and stops as soon as
+// the LIMIT is met, so a sort here would build the whole tree first.
+// reply_count is every visible reply, so the caller can tell which chirps had
+// replies cut off.
+func (q *Queries) SelectThread(ctx context.Context, arg SelectThreadParams) ([]SelectThreadRow, error) {
+	rows, err := q.db.QueryContext(ctx, selectThread,
+		arg.RootID,
+		arg.RepliesPerChirp,
+		arg.MaxDepth,
+		arg.MaxChirps,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SelectThreadRow
+	for rows.Next() {
+		var i SelectThreadRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.LikeCount,
+			&i.Depth,
+			&i.ReplyCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const selectThreadRoot = `-- name: SelectThreadRoot :one
+WITH RECURSIVE ancestors AS (
+    SELECT chirps.id, chirps.in_reply_to FROM chirps
+    WHERE chirps.id = $1 AND chirps.deleted_at IS NULL
+    UNION ALL
+    SELECT c.id, c.in_reply_to FROM chirps c
+    JOIN ancestors a ON c.id = a.in_reply_to
+    WHERE c.deleted_at IS NULL
+)
+SELECT ancestors.id FROM ancestors
+WHERE NOT EXISTS (
+    SELECT 1 FROM chirps AS parent
+    WHERE parent.id = ancestors.in_reply_to AND parent.deleted_at IS NULL
+)
+LIMIT 1
+`
+
+// A deleted chirp cuts the thread: replies to it are roots of their own.
+func (q *Queries) SelectThreadRoot(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, selectThreadRoot, id)
+	err := row.Scan(&id)
+	return id, err
+}
+
+const selectTimelinePage = `-- name: SelectTimelinePage :many
+SELECT timeline.id, timeline.created_at, timeline.updated_at, timeline.body, timeline.user_id, timeline.in_reply_to, timeline.like_count, timeline.deleted_at, timeline.deleted_by FROM follows
+CROSS JOIN LATERAL (
+    SELECT id, created_at, updated_at, body, user_id, in_reply_to, like_count, deleted_at, deleted_by FROM chirps
+    WHERE chirps.user_id = follows.followee_id AND chirps.deleted_at IS NULL
+    AND (
+        $1::timestamp IS NULL
+        OR (chirps.created_at, chirps.id) < ($1::timestamp, $2::uuid)
+    )
+    ORDER BY chirps.created_at DESC, chirps.id DESC
+    LIMIT $3
+) AS timeline
+WHERE follows.follower_id = $4
+ORDER BY timeline.created_at DESC, timeline.id DESC
+LIMIT $3
+`
+
+type SelectTimelinePageParams struct {
+	BeforeCreatedAt sql.NullTime
+	BeforeID        uuid.NullUUID
+	PageSize        int32
+	FollowerID      uuid.UUID
+}
+
+// Each followed account contributes at most page_size chirps, read backwards
+// along the (user_id, created_at, id) index, so a page costs one short index
+// scan per followed account however much they have posted.
+func (q *Queries) SelectTimelinePage(ctx context.Context, arg SelectTimelinePageParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, selectTimelinePage,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+		arg.PageSize,
+		arg.FollowerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.LikeCount,
+			&i.DeletedAt,
+			&i.DeletedBy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+WITH prior AS (
+    INSERT INTO chirp_revisions (id, chirp_id, body, created_at, replaced_at)
+    SELECT gen_random_uuid(), chirps.id, chirps.body, chirps.updated_at, NOW()
+    FROM chirps
+    WHERE chirps.id = $1 AND chirps.deleted_at IS NULL
+)
+UPDATE chirps
+SET body = $2, updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, like_count, deleted_at, deleted_by
+`
+
+type UpdateChirpBodyParams struct {
+	ID   uuid.UUID
+	Body string
+}
+
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.ID, arg.Body)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.LikeCount,
+		&i.DeletedAt,
+		&i.DeletedBy,
 	)
 	return i, err
 }
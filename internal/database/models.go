@@ -17,15 +17,117 @@ type Chirp struct {
 	UpdatedAt time.Time
 	Body      string
 	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
+	LikeCount int32
+	DeletedAt sql.NullTime
+	DeletedBy uuid.NullUUID
 }
 
-type RefreshToken struct {
-	Token     string
+type ChirpHashtag struct {
+	ChirpID   uuid.UUID
+	Tag       string
 	CreatedAt time.Time
-	UpdatedAt time.Time
+}
+
+type ChirpMention struct {
+	ChirpID uuid.UUID
+	UserID  uuid.UUID
+	Handle  string
+}
+
+type ChirpRevision struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	Body       string
+	CreatedAt  time.Time
+	ReplacedAt time.Time
+}
+
+type ChirpSearch struct {
+	ChirpID  uuid.UUID
+	Document interface{}
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type Like struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type Medium struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	ChirpID      uuid.NullUUID
+	Position     sql.NullInt32
+	ContentType  string
+	FileKey      string
+	ThumbnailKey string
+	Width        int32
+	Height       int32
+	SizeBytes    int32
+	CreatedAt    time.Time
+}
+
+type ModerationAction struct {
+	ID          uuid.UUID
+	ReportID    uuid.NullUUID
+	ModeratorID uuid.NullUUID
+	Action      string
+	ChirpID     uuid.NullUUID
+	UserID      uuid.NullUUID
+	Note        string
+	CreatedAt   time.Time
+}
+
+type Notification struct {
+	ID        uuid.UUID
 	UserID    uuid.UUID
-	ExpiresAt time.Time
-	RevokedAt sql.NullTime
+	Type      string
+	ChirpID   uuid.UUID
+	ActorID   uuid.UUID
+	CreatedAt time.Time
+	ReadAt    sql.NullTime
+}
+
+type ProfanityWord struct {
+	Word     string
+	Strategy string
+}
+
+type RefreshToken struct {
+	TokenHash  string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	ExpiresAt  time.Time
+	RevokedAt  sql.NullTime
+	FamilyID   uuid.UUID
+	ReplacedBy sql.NullString
+	DeviceName string
+	UserAgent  string
+	IpAddress  string
+	LastUsedAt time.Time
+}
+
+type Report struct {
+	ID             uuid.UUID
+	ReporterID     uuid.UUID
+	ChirpID        uuid.NullUUID
+	ReportedUserID uuid.UUID
+	Reason         string
+	Details        string
+	Status         string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	ResolvedAt     sql.NullTime
+	ResolvedBy     uuid.NullUUID
+	ChirpBody      sql.NullString
 }
 
 type User struct {
@@ -35,4 +137,7 @@ type User struct {
 	Email          string
 	HashedPassword string
 	IsChirpyRed    bool
+	Role           string
+	SuspendedUntil sql.NullTime
+	Status         string
 }
@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
 )
@@ -20,7 +21,7 @@ VALUES (
     $1,
     $2
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_until, status
 `
 
 type CreateUserParams struct {
@@ -38,6 +39,9 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.Status,
 	)
 	return i, err
 }
@@ -52,7 +56,7 @@ func (q *Queries) DeleteUsers(ctx context.Context) error {
 }
 
 const login = `-- name: Login :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red FROM users
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_until, status FROM users
 WHERE email = $1
 `
 
@@ -66,6 +70,142 @@ func (q *Queries) Login(ctx context.Context, email string) (User, error) {
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.Status,
+	)
+	return i, err
+}
+
+const selectAccountRestriction = `-- name: SelectAccountRestriction :one
+SELECT status, suspended_until FROM users
+WHERE id = $1
+AND (status = 'banned' OR (status = 'suspended' AND suspended_until > NOW()))
+`
+
+type SelectAccountRestrictionRow struct {
+	Status         string
+	SuspendedUntil sql.NullTime
+}
+
+// Returns a row only if the user is banned or currently suspended.
+func (q *Queries) SelectAccountRestriction(ctx context.Context, id uuid.UUID) (SelectAccountRestrictionRow, error) {
+	row := q.db.QueryRowContext(ctx, selectAccountRestriction, id)
+	var i SelectAccountRestrictionRow
+	err := row.Scan(&i.Status, &i.SuspendedUntil)
+	return i, err
+}
+
+const selectUserID = `-- name: SelectUserID :one
+SELECT id FROM users
+WHERE id = $1
+`
+
+func (q *Queries) SelectUserID(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, selectUserID, id)
+	err := row.Scan(&id)
+	return id, err
+}
+
+const selectUserRole = `-- name: SelectUserRole :one
+SELECT role FROM users
+WHERE id = $1
+`
+
+func (q *Queries) SelectUserRole(ctx context.Context, id uuid.UUID) (string, error) {
+	row := q.db.QueryRowContext(ctx, selectUserRole, id)
+	var role string
+	err := row.Scan(&role)
+	return role, err
+}
+
+const setUserRole = `-- name: SetUserRole :one
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_until, status
+`
+
+type SetUserRoleParams struct {
+	ID   uuid.UUID
+	Role string
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserRole, arg.ID, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.Status,
+	)
+	return i, err
+}
+
+const setUserRoleByEmail = `-- name: SetUserRoleByEmail :execrows
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE email = $1
+`
+
+type SetUserRoleByEmailParams struct {
+	Email string
+	Role  string
+}
+
+func (q *Queries) SetUserRoleByEmail(ctx context.Context, arg SetUserRoleByEmailParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setUserRoleByEmail, arg.Email, arg.Role)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setUserStatus = `-- name: SetUserStatus :one
+WITH updated AS (
+    UPDATE users
+    SET status = $1::text, suspended_until = $2::timestamp, updated_at = NOW()
+    WHERE users.id = $3
+    RETURNING users.id, users.email, users.status, users.suspended_until
+), revoked AS (
+    UPDATE refresh_tokens
+    SET revoked_at = NOW(), updated_at = NOW()
+    WHERE $1::text <> 'active'
+    AND refresh_tokens.user_id = (SELECT updated.id FROM updated)
+    AND refresh_tokens.revoked_at IS NULL
+)
+SELECT id, email, status, suspended_until FROM updated
+`
+
+type SetUserStatusParams struct {
+	Status         string
+	SuspendedUntil sql.NullTime
+	ID             uuid.UUID
+}
+
+type SetUserStatusRow struct {
+	ID             uuid.UUID
+	Email          string
+	Status         string
+	SuspendedUntil sql.NullTime
+}
+
+// Sets the account status and, unless the user is being made active again,
+// revokes all of their refresh tokens in the same statement.
+func (q *Queries) SetUserStatus(ctx context.Context, arg SetUserStatusParams) (SetUserStatusRow, error) {
+	row := q.db.QueryRowContext(ctx, setUserStatus, arg.Status, arg.SuspendedUntil, arg.ID)
+	var i SetUserStatusRow
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Status,
+		&i.SuspendedUntil,
 	)
 	return i, err
 }
@@ -74,7 +214,7 @@ const updateUsers = `-- name: UpdateUsers :one
 UPDATE users
 SET email = $1, hashed_password = $2, updated_at = NOW()
 WHERE id = $3
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_until, status
 `
 
 type UpdateUsersParams struct {
@@ -93,6 +233,9 @@ func (q *Queries) UpdateUsers(ctx context.Context, arg UpdateUsersParams) (User,
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.Status,
 	)
 	return i, err
 }
@@ -101,7 +244,7 @@ const upgradeToChirpyRed = `-- name: UpgradeToChirpyRed :one
 UPDATE users
 SET is_chirpy_red = true
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_until, status
 `
 
 func (q *Queries) UpgradeToChirpyRed(ctx context.Context, id uuid.UUID) (User, error) {
@@ -114,6 +257,9 @@ func (q *Queries) UpgradeToChirpyRed(ctx context.Context, id uuid.UUID) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.Status,
 	)
 	return i, err
 }
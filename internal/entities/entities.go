@@ -0,0 +1,96 @@
+// Package entities finds #hashtags and @mentions in chirp bodies.
+package entities
+
+import (
+	"strings"
+	"unicode"
+)
+
+type Type string
+
+const (
+	TypeHashtag Type = "hashtag"
+	TypeMention Type = "mention"
+)
+
+// Entity is a hashtag or mention in a body. Start and End are offsets in
+// Unicode code points, with End exclusive, and cover the leading # or @.
+// Text is the normalised tag or handle without its sigil.
+type Entity struct {
+	Type  Type
+	Start int
+	End   int
+	Text  string
+}
+
+// Parse returns the entities in body in the order they appear. A sigil only
+// starts an entity at the beginning of the body or after a character that
+// cannot be part of one, so "a@b.c" is not a mention and "x#1" is not a
+// hashtag. Hashtags made only of digits are ignored.
+func Parse(body string) []Entity {
+	runes := []rune(body)
+	var found []Entity
+	for i := 0; i < len(runes); i++ {
+		var typ Type
+		switch runes[i] {
+		case '#':
+			typ = TypeHashtag
+		case '@':
+			typ = TypeMention
+		default:
+			continue
+		}
+		if i > 0 && (isTagRune(runes[i-1]) || runes[i-1] == '#' || runes[i-1] == '@') {
+			continue
+		}
+
+		end := i + 1
+		for end < len(runes) && (isTagRune(runes[end]) || typ == TypeMention && isHandlePunct(runes[end])) {
+			end++
+		}
+		// Handles may contain dots and dashes but not end with them, so
+		// "thanks @alice." mentions alice.
+		for end > i+1 && isHandlePunct(runes[end-1]) {
+			end--
+		}
+
+		text := string(runes[i+1 : end])
+		if text == "" || typ == TypeHashtag && strings.IndexFunc(text, func(r rune) bool { return !unicode.IsDigit(r) }) < 0 {
+			continue
+		}
+		found = append(found, Entity{Type: typ, Start: i, End: end, Text: strings.ToLower(text)})
+		i = end - 1
+	}
+	return found
+}
+
+// Hashtags returns the distinct tags in body.
+func Hashtags(body string) []string {
+	return distinct(body, TypeHashtag)
+}
+
+// Mentions returns the distinct handles mentioned in body.
+func Mentions(body string) []string {
+	return distinct(body, TypeMention)
+}
+
+func distinct(body string, typ Type) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, e := range Parse(body) {
+		if e.Type != typ || seen[e.Text] {
+			continue
+		}
+		seen[e.Text] = true
+		out = append(out, e.Text)
+	}
+	return out
+}
+
+func isTagRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || r == '_'
+}
+
+func isHandlePunct(r rune) bool {
+	return r == '.' || r == '-' || r == '+'
+}
@@ -0,0 +1,46 @@
+package entities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []Entity
+	}{
+		{name: "None", body: "just words", want: nil},
+		{name: "Hashtag", body: "I love #Go", want: []Entity{{TypeHashtag, 7, 10, "go"}}},
+		{name: "Mention", body: "@alice hi", want: []Entity{{TypeMention, 0, 6, "alice"}}},
+		{name: "Trailing punctuation", body: "thanks @alice.smith. #go!", want: []Entity{
+			{TypeMention, 7, 19, "alice.smith"},
+			{TypeHashtag, 21, 24, "go"},
+		}},
+		{name: "Email is not a mention", body: "mail bob@example.com", want: nil},
+		{name: "Mid-word hash", body: "C#sharp x#1", want: nil},
+		{name: "Digits only", body: "#2025 #year2025", want: []Entity{{TypeHashtag, 6, 15, "year2025"}}},
+		{name: "Bare sigils", body: "# @ ## @@", want: nil},
+		{name: "Offsets are code points", body: "café #crème", want: []Entity{{TypeHashtag, 5, 11, "crème"}}},
+		{name: "Dot not in hashtag", body: "#go.dev", want: []Entity{{TypeHashtag, 0, 3, "go"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.body); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %v, want %v", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDistinct(t *testing.T) {
+	body := "#Go @bob #go @Bob #sql"
+	if got, want := Hashtags(body), []string{"go", "sql"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Hashtags() = %v, want %v", got, want)
+	}
+	if got, want := Mentions(body), []string{"bob"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Mentions() = %v, want %v", got, want)
+	}
+}
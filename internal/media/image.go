@@ -0,0 +1,315 @@
+// Package media validates and re-encodes uploaded images and stores them.
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	// MaxPixels bounds the decoded size of an upload, so a small file that
+	// claims huge dimensions cannot exhaust memory.
+	MaxPixels = 24_000_000
+	// MaxGIFFrames and MaxGIFPixels bound an animated GIF by frame count and
+	// by the pixels of all frames together.
+	MaxGIFFrames = 200
+	MaxGIFPixels = 100_000_000
+	// ThumbnailSize is the longest side of a thumbnail.
+	ThumbnailSize = 320
+
+	jpegQuality = 90
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported image type")
+	ErrTooLarge        = errors.New("image is too large")
+)
+
+// Image is an upload after processing. Data and Thumbnail hold freshly
+// encoded files, so no metadata from the original (EXIF, XMP, comments)
+// survives.
+type Image struct {
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+	Data        []byte
+
+	ThumbnailContentType string
+	ThumbnailExt         string
+	Thumbnail            []byte
+}
+
+// Process sniffs data, rejects anything that is not a JPEG, PNG or GIF,
+// re-encodes it and makes a thumbnail. A JPEG's EXIF orientation is applied
+// to the pixels before the EXIF data is dropped.
+func Process(data []byte) (Image, error) {
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return Image{}, fmt.Errorf("%w: %s", ErrUnsupportedType, contentType)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, fmt.Errorf("%w: %v", ErrUnsupportedType, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return Image{}, ErrTooLarge
+	}
+
+	img := Image{ContentType: contentType}
+	var first image.Image
+	var buf bytes.Buffer
+	switch contentType {
+	case "image/jpeg":
+		src, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, fmt.Errorf("%w: %v", ErrUnsupportedType, err)
+		}
+		first = orient(src, jpegOrientation(data))
+		if err := jpeg.Encode(&buf, first, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return Image{}, err
+		}
+		img.Ext = "jpg"
+	case "image/png":
+		src, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, fmt.Errorf("%w: %v", ErrUnsupportedType, err)
+		}
+		first = src
+		if err := png.Encode(&buf, src); err != nil {
+			return Image{}, err
+		}
+		img.Ext = "png"
+	case "image/gif":
+		// Every frame is decoded up front, so count them before decoding.
+		frames, err := gifFrameCount(data)
+		if err != nil {
+			return Image{}, fmt.Errorf("%w: %v", ErrUnsupportedType, err)
+		}
+		if frames == 0 {
+			return Image{}, fmt.Errorf("%w: GIF has no frames", ErrUnsupportedType)
+		}
+		if frames > MaxGIFFrames || frames*cfg.Width*cfg.Height > MaxGIFPixels {
+			return Image{}, ErrTooLarge
+		}
+		src, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, fmt.Errorf("%w: %v", ErrUnsupportedType, err)
+		}
+		first = src.Image[0]
+		// EncodeAll writes frames, timing and loop count only, which drops
+		// comment and application extensions.
+		if err := gif.EncodeAll(&buf, src); err != nil {
+			return Image{}, err
+		}
+		img.Ext = "gif"
+	}
+	img.Data = buf.Bytes()
+	img.Width, img.Height = first.Bounds().Dx(), first.Bounds().Dy()
+
+	thumb := thumbnail(first)
+	buf = bytes.Buffer{}
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: jpegQuality})
+		img.ThumbnailContentType, img.ThumbnailExt = "image/jpeg", "jpg"
+	} else {
+		err = png.Encode(&buf, thumb)
+		img.ThumbnailContentType, img.ThumbnailExt = "image/png", "png"
+	}
+	if err != nil {
+		return Image{}, err
+	}
+	img.Thumbnail = buf.Bytes()
+	return img, nil
+}
+
+// thumbnail scales src to fit within ThumbnailSize on both sides. Images
+// already that small are copied unchanged.
+func thumbnail(src image.Image) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > ThumbnailSize || h > ThumbnailSize {
+		if w >= h {
+			w, h = ThumbnailSize, max(1, h*ThumbnailSize/w)
+		} else {
+			w, h = max(1, w*ThumbnailSize/h), ThumbnailSize
+		}
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+	return dst
+}
+
+// gifFrameCount walks the block structure of a GIF without decompressing
+// anything and returns the number of frames.
+func gifFrameCount(data []byte) (int, error) {
+	errMalformed := errors.New("malformed GIF")
+	if len(data) < 13 {
+		return 0, errMalformed
+	}
+	i := 13
+	if flags := data[10]; flags&0x80 != 0 {
+		i += 3 << (flags&0x07 + 1)
+	}
+
+	// skipSubBlocks advances past a run of length-prefixed sub-blocks and
+	// its zero terminator.
+	skipSubBlocks := func() bool {
+		for i < len(data) {
+			n := int(data[i])
+			i++
+			if n == 0 {
+				return true
+			}
+			i += n
+		}
+		return false
+	}
+
+	frames := 0
+	for i < len(data) {
+		switch data[i] {
+		case 0x3B: // trailer
+			return frames, nil
+		case 0x21: // extension: label, then sub-blocks
+			i += 2
+			if !skipSubBlocks() {
+				return 0, errMalformed
+			}
+		case 0x2C: // image descriptor, optional local color table, LZW data
+			if i+10 > len(data) {
+				return 0, errMalformed
+			}
+			flags := data[i+9]
+			i += 10
+			if flags&0x80 != 0 {
+				i += 3 << (flags&0x07 + 1)
+			}
+			i++ // LZW minimum code size
+			if !skipSubBlocks() {
+				return 0, errMalformed
+			}
+			frames++
+		default:
+			return 0, errMalformed
+		}
+	}
+	// Like image/gif, tolerate a missing trailer.
+	return frames, nil
+}
+
+// jpegOrientation returns the EXIF orientation (1-8) of a JPEG, or 1 if it
+// has none or the EXIF data cannot be read.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			// Start of scan or end of image: no more metadata segments.
+			return 1
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+size]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + size
+	}
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for n := 0; n < count; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			o := int(order.Uint16(tiff[entry+8:]))
+			if o < 1 || o > 8 {
+				return 1
+			}
+			return o
+		}
+	}
+	return 1
+}
+
+// orient applies the transform that an EXIF orientation calls for to display
+// src upright.
+func orient(src image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // flip horizontally
+				sx, sy = w-1-x, y
+			case 3: // rotate 180°
+				sx, sy = w-1-x, h-1-y
+			case 4: // flip vertically
+				sx, sy = x, h-1-y
+			case 5: // transpose
+				sx, sy = y, x
+			case 6: // rotate 90° clockwise
+				sx, sy = y, h-1-x
+			case 7: // transverse
+				sx, sy = w-1-y, h-1-x
+			case 8: // rotate 90° counter-clockwise
+				sx, sy = w-1-y, x
+			}
+			dst.Set(x, y, src.At(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+	return dst
+}
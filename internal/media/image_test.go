@@ -0,0 +1,188 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// testImage is w×h, blue except for a red top-left corner a quarter of
+// each side (at least one pixel), so orientation changes can be told apart.
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{B: 255, A: 255})
+		}
+	}
+	for y := 0; y < max(1, h/4); y++ {
+		for x := 0; x < max(1, w/4); x++ {
+			img.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+	return img
+}
+
+// withEXIF inserts an APP1 segment carrying orientation (and a made-up GPS
+// marker) after the SOI marker of a JPEG.
+func withEXIF(t *testing.T, jpg []byte, orientation uint16) []byte {
+	t.Helper()
+	var tiff bytes.Buffer
+	tiff.WriteString("MM")
+	binary.Write(&tiff, binary.BigEndian, uint16(42))
+	binary.Write(&tiff, binary.BigEndian, uint32(8))
+	binary.Write(&tiff, binary.BigEndian, uint16(1))
+	binary.Write(&tiff, binary.BigEndian, []uint16{0x0112, 3})
+	binary.Write(&tiff, binary.BigEndian, uint32(1))
+	binary.Write(&tiff, binary.BigEndian, []uint16{orientation, 0})
+	binary.Write(&tiff, binary.BigEndian, uint32(0))
+	tiff.WriteString("GPS-SECRET")
+
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, jpg[:2]...)
+	out = append(out, segment...)
+	return append(out, jpg[2:]...)
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcessJPEG(t *testing.T) {
+	data := withEXIF(t, encodeJPEG(t, testImage(40, 20)), 6)
+	if got := jpegOrientation(data); got != 6 {
+		t.Fatalf("jpegOrientation() = %d, want 6", got)
+	}
+
+	img, err := Process(data)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if img.ContentType != "image/jpeg" || img.Ext != "jpg" {
+		t.Errorf("type = %s/%s, want image/jpeg/jpg", img.ContentType, img.Ext)
+	}
+	if img.Width != 20 || img.Height != 40 {
+		t.Errorf("size = %dx%d, want 20x40 after rotating", img.Width, img.Height)
+	}
+	if bytes.Contains(img.Data, []byte("Exif")) || bytes.Contains(img.Data, []byte("GPS-SECRET")) {
+		t.Error("processed JPEG still contains EXIF data")
+	}
+
+	decoded, err := jpeg.Decode(bytes.NewReader(img.Data))
+	if err != nil {
+		t.Fatalf("decoding output: %v", err)
+	}
+	// Rotating 90° clockwise moves the top-left corner to the top-right.
+	if r, _, b, _ := decoded.At(17, 3).RGBA(); r < b {
+		t.Errorf("top-right corner is not red after rotation")
+	}
+}
+
+func TestProcessPNGThumbnail(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, testImage(1000, 500))
+
+	img, err := Process(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	thumb, err := png.DecodeConfig(bytes.NewReader(img.Thumbnail))
+	if err != nil {
+		t.Fatalf("decoding thumbnail: %v", err)
+	}
+	if thumb.Width != ThumbnailSize || thumb.Height != ThumbnailSize/2 {
+		t.Errorf("thumbnail = %dx%d, want %dx%d", thumb.Width, thumb.Height, ThumbnailSize, ThumbnailSize/2)
+	}
+	if img.ThumbnailContentType != "image/png" {
+		t.Errorf("thumbnail type = %s, want image/png", img.ThumbnailContentType)
+	}
+}
+
+func TestProcessAnimatedGIF(t *testing.T) {
+	anim := &gif.GIF{}
+	for i := 0; i < 3; i++ {
+		anim.Image = append(anim.Image, image.NewPaletted(image.Rect(0, 0, 8, 8), palette.Plan9))
+		anim.Delay = append(anim.Delay, 10)
+	}
+	var buf bytes.Buffer
+	gif.EncodeAll(&buf, anim)
+
+	if n, err := gifFrameCount(buf.Bytes()); n != 3 || err != nil {
+		t.Fatalf("gifFrameCount() = %d, %v, want 3", n, err)
+	}
+	img, err := Process(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	out, err := gif.DecodeAll(bytes.NewReader(img.Data))
+	if err != nil || len(out.Image) != 3 {
+		t.Errorf("processed GIF has %d frames (err %v), want 3", len(out.Image), err)
+	}
+}
+
+func TestProcessRejects(t *testing.T) {
+	huge := &bytes.Buffer{}
+	png.Encode(huge, image.NewGray(image.Rect(0, 0, 6000, 5000)))
+	small := &bytes.Buffer{}
+	png.Encode(small, testImage(10, 10))
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{name: "Text", data: []byte("hello, world"), want: ErrUnsupportedType},
+		{name: "HTML", data: []byte("<html><script>alert(1)</script></html>"), want: ErrUnsupportedType},
+		{name: "Truncated PNG", data: small.Bytes()[:small.Len()-20], want: ErrUnsupportedType},
+		{name: "Too many pixels", data: huge.Bytes(), want: ErrTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Process(tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("Process() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// Where the red top-left pixel of a 3×2 image ends up.
+	tests := []struct {
+		orientation int
+		wantX       int
+		wantY       int
+	}{
+		{1, 0, 0},
+		{2, 2, 0},
+		{3, 2, 1},
+		{4, 0, 1},
+		{5, 0, 0},
+		{6, 1, 0},
+		{7, 1, 2},
+		{8, 0, 2},
+	}
+
+	for _, tt := range tests {
+		got := orient(testImage(3, 2), tt.orientation)
+		if r, _, _, _ := got.At(tt.wantX, tt.wantY).RGBA(); r == 0 {
+			t.Errorf("orientation %d: red pixel not at (%d, %d)", tt.orientation, tt.wantX, tt.wantY)
+		}
+	}
+}
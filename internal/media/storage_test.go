@@ -0,0 +1,53 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal() error = %v", err)
+	}
+
+	if err := store.Put(ctx, "a.png", strings.NewReader("data")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	obj, err := store.Open(ctx, "a.png")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	got, _ := io.ReadAll(obj)
+	obj.Close()
+	if string(got) != "data" {
+		t.Errorf("read %q, want %q", got, "data")
+	}
+
+	if err := store.Delete(ctx, "a.png"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Open(ctx, "a.png"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open() after Delete error = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "a.png"); err != nil {
+		t.Errorf("Delete() of missing key error = %v", err)
+	}
+}
+
+func TestValidKey(t *testing.T) {
+	for _, key := range []string{"", ".", "..", "../etc/passwd", "a/b", `a\b`, ".hidden", "a b"} {
+		if ValidKey(key) {
+			t.Errorf("ValidKey(%q) = true", key)
+		}
+	}
+	for _, key := range []string{"4b0c.jpg", "4b0c_thumb.png", "a-b.gif"} {
+		if !ValidKey(key) {
+			t.Errorf("ValidKey(%q) = false", key)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package profanity
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+type Strategy string
+
+const (
+	// StrategyMask replaces the word with a fixed "****".
+	StrategyMask Strategy = "mask"
+	// StrategyPreserveLength replaces every character of the word with "*".
+	StrategyPreserveLength Strategy = "preserve"
+	// StrategyReject refuses the whole text.
+	StrategyReject Strategy = "reject"
+)
+
+const mask = "****"
+
+type Rule struct {
+	Word     string
+	Strategy Strategy
+}
+
+// Loader returns the current set of rules. It is called once by New and again
+// on every Reload.
+type Loader func(ctx context.Context) ([]Rule, error)
+
+// RejectedError is returned by Clean when the text contains a word whose
+// strategy is StrategyReject.
+type RejectedError struct {
+	Word string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("text contains prohibited word %q", e.Word)
+}
+
+// Filter matches words case-insensitively and independent of Unicode
+// normalization form. It is safe for concurrent use, including while a
+// Reload is in progress.
+type Filter struct {
+	load  Loader
+	rules atomic.Pointer[map[string]Strategy]
+}
+
+func New(ctx context.Context, load Loader) (*Filter, error) {
+	f := &Filter{load: load}
+	if err := f.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Reload fetches the rules again. On error the previous rules stay in effect.
+func (f *Filter) Reload(ctx context.Context) error {
+	rules, err := f.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	m := make(map[string]Strategy, len(rules))
+	for _, rule := range rules {
+		word := normalize(rule.Word)
+		if word == "" {
+			continue
+		}
+		strategy := rule.Strategy
+		if strategy == "" {
+			strategy = StrategyMask
+		}
+		if !strategy.valid() {
+			return fmt.Errorf("unknown strategy %q for word %q", strategy, rule.Word)
+		}
+		m[word] = strategy
+	}
+
+	f.rules.Store(&m)
+	return nil
+}
+
+// Len reports how many words are currently loaded.
+func (f *Filter) Len() int {
+	return len(*f.rules.Load())
+}
+
+// Clean returns text with every listed word replaced according to its
+// strategy. Words are runs of letters, digits and combining marks, so
+// surrounding punctuation and whitespace are left untouched.
+func (f *Filter) Clean(text string) (string, error) {
+	rules := *f.rules.Load()
+
+	var b strings.Builder
+	b.Grow(len(text))
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !isWordRune(r) {
+			b.WriteString(text[i : i+size])
+			i += size
+			continue
+		}
+
+		end := i
+		for end < len(text) {
+			r, size := utf8.DecodeRuneInString(text[end:])
+			if !isWordRune(r) {
+				break
+			}
+			end += size
+		}
+
+		word := text[i:end]
+		switch rules[normalize(word)] {
+		case StrategyMask:
+			b.WriteString(mask)
+		case StrategyPreserveLength:
+			b.WriteString(strings.Repeat("*", utf8.RuneCountInString(norm.NFC.String(word))))
+		case StrategyReject:
+			return "", &RejectedError{Word: word}
+		default:
+			b.WriteString(word)
+		}
+		i = end
+	}
+
+	return b.String(), nil
+}
+
+// FileLoader reads rules from a text file with one word per line, optionally
+// followed by a strategy ("kerfuffle preserve"). Blank lines and lines
+// starting with # are ignored.
+func FileLoader(path string) Loader {
+	return func(ctx context.Context) ([]Rule, error) {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return ParseRules(file)
+	}
+}
+
+func ParseRules(r io.Reader) ([]Rule, error) {
+	rules := []Rule{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		rule := Rule{Word: fields[0], Strategy: StrategyMask}
+		switch len(fields) {
+		case 1:
+		case 2:
+			rule.Strategy = Strategy(fields[1])
+		default:
+			return nil, fmt.Errorf("line %d: expected \"word [strategy]\"", line)
+		}
+		if !rule.Strategy.valid() {
+			return nil, fmt.Errorf("line %d: unknown strategy %q", line, rule.Strategy)
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Static returns a Loader that always yields the given rules.
+func Static(rules ...Rule) Loader {
+	return func(ctx context.Context) ([]Rule, error) {
+		return rules, nil
+	}
+}
+
+func (s Strategy) valid() bool {
+	switch s {
+	case StrategyMask, StrategyPreserveLength, StrategyReject:
+		return true
+	}
+	return false
+}
+
+// normalize maps a word to its comparison key: compatibility-composed and
+// case-folded, so "KERFUFFLE", "kerfuffle" and full-width variants match.
+func normalize(word string) string {
+	return norm.NFC.String(cases.Fold().String(norm.NFKC.String(word)))
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
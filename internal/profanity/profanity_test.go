@@ -0,0 +1,116 @@
+package profanity
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClean(t *testing.T) {
+	f, err := New(context.Background(), Static(
+		Rule{Word: "kerfuffle", Strategy: StrategyMask},
+		Rule{Word: "sharbert", Strategy: StrategyPreserveLength},
+		Rule{Word: "caf\u00e9", Strategy: StrategyMask},
+		Rule{Word: "fornax", Strategy: StrategyReject},
+	))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{name: "No match", text: "hello world", want: "hello world"},
+		{name: "Mixed case", text: "KerFuffle time", want: "**** time"},
+		{name: "Trailing punctuation", text: "what a kerfuffle!", want: "what a ****!"},
+		{name: "Comma and newline", text: "kerfuffle,\nkerfuffle\tok", want: "****,\n****\tok"},
+		{name: "Length preserving", text: "sharbert.", want: "********."},
+		{name: "Substring is not a match", text: "kerfuffles", want: "kerfuffles"},
+		{name: "Decomposed form", text: "cafe\u0301 au lait", want: "**** au lait"},
+		{name: "Full-width form", text: "ｋｅｒｆｕｆｆｌｅ", want: "****"},
+		{name: "Reject", text: "oh fornax", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := f.Clean(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Clean() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				var rejected *RejectedError
+				if !errors.As(err, &rejected) {
+					t.Errorf("Clean() error = %T, want *RejectedError", err)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Clean() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules(strings.NewReader("# comment\n\nkerfuffle\nsharbert preserve\n"))
+	if err != nil {
+		t.Fatalf("ParseRules() error = %v", err)
+	}
+	want := []Rule{
+		{Word: "kerfuffle", Strategy: StrategyMask},
+		{Word: "sharbert", Strategy: StrategyPreserveLength},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("ParseRules() = %v, want %v", rules, want)
+	}
+	for i := range want {
+		if rules[i] != want[i] {
+			t.Errorf("rule %d = %v, want %v", i, rules[i], want[i])
+		}
+	}
+
+	if _, err := ParseRules(strings.NewReader("kerfuffle shout\n")); err == nil {
+		t.Error("ParseRules() expected error for unknown strategy")
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(path, []byte("kerfuffle\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := New(context.Background(), FileLoader(path))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got, _ := f.Clean("fornax"); got != "fornax" {
+		t.Fatalf("Clean() before reload = %q", got)
+	}
+
+	if err := os.WriteFile(path, []byte("kerfuffle\nfornax\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if got, _ := f.Clean("fornax"); got != "****" {
+		t.Errorf("Clean() after reload = %q, want ****", got)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Reload(context.Background()); err == nil {
+		t.Fatal("Reload() expected error for missing file")
+	}
+	if f.Len() != 2 {
+		t.Errorf("Len() after failed reload = %d, want previous 2", f.Len())
+	}
+}
@@ -0,0 +1,144 @@
+// Package pubsub is an in-process broadcast broker with a short replay
+// history, so a subscriber that reconnects can pick up where it left off.
+package pubsub
+
+import "sync"
+
+// Event is a published value with its position in the broker's sequence.
+// IDs start at 1 and increase by one per Publish.
+type Event[T any] struct {
+	ID    uint64
+	Value T
+}
+
+// Broker fans each published value out to every subscriber. Publish never
+// blocks: a subscriber whose buffer is full is dropped and its channel
+// closed, and it is up to the consumer to resubscribe from the last ID it
+// saw.
+type Broker[T any] struct {
+	mu      sync.Mutex
+	lastID  uint64
+	history []Event[T] // ring buffer; the oldest event is at start
+	start   int
+	subs    map[*Subscription[T]]struct{}
+	bufSize int
+	closed  bool
+}
+
+// Subscription receives events on C until it is cancelled, dropped for
+// falling behind, or the broker is closed.
+type Subscription[T any] struct {
+	C <-chan Event[T]
+
+	c      chan Event[T]
+	broker *Broker[T]
+}
+
+// New returns a broker that remembers the last historySize events and gives
+// each subscriber a buffer of bufSize events.
+func New[T any](historySize, bufSize int) *Broker[T] {
+	return &Broker[T]{
+		history: make([]Event[T], 0, historySize),
+		subs:    map[*Subscription[T]]struct{}{},
+		bufSize: bufSize,
+	}
+}
+
+// Publish assigns v the next ID and delivers it to every subscriber.
+func (b *Broker[T]) Publish(v T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+
+	b.lastID++
+	e := Event[T]{ID: b.lastID, Value: v}
+	if len(b.history) < cap(b.history) {
+		b.history = append(b.history, e)
+	} else if cap(b.history) > 0 {
+		b.history[b.start] = e
+		b.start = (b.start + 1) % len(b.history)
+	}
+
+	for s := range b.subs {
+		select {
+		case s.c <- e:
+		default:
+			b.drop(s)
+		}
+	}
+}
+
+// Subscribe starts a subscription. If lastID is not zero, events after it
+// that are still in the history are delivered first. complete is false if
+// some events after lastID have already been forgotten, or lastID is from
+// a previous broker, so the subscriber has missed events.
+func (b *Broker[T]) Subscribe(lastID uint64) (sub *Subscription[T], complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var backlog []Event[T]
+	complete = true
+	if lastID != 0 {
+		backlog = b.since(lastID)
+		oldest := b.lastID - uint64(len(b.history)) + 1
+		complete = lastID <= b.lastID && lastID+1 >= oldest
+	}
+
+	c := make(chan Event[T], b.bufSize+len(backlog))
+	for _, e := range backlog {
+		c <- e
+	}
+	sub = &Subscription[T]{C: c, c: c, broker: b}
+	if b.closed {
+		close(c)
+		return sub, complete
+	}
+	b.subs[sub] = struct{}{}
+	return sub, complete
+}
+
+func (b *Broker[T]) since(lastID uint64) []Event[T] {
+	var out []Event[T]
+	for i := range b.history {
+		e := b.history[(b.start+i)%len(b.history)]
+		if e.ID > lastID {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Close ends every subscription. Later Publish calls are ignored and later
+// subscriptions are closed immediately.
+func (b *Broker[T]) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for s := range b.subs {
+		b.drop(s)
+	}
+}
+
+// Len reports the number of active subscriptions.
+func (b *Broker[T]) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+func (b *Broker[T]) drop(s *Subscription[T]) {
+	delete(b.subs, s)
+	close(s.c)
+}
+
+// Cancel ends the subscription. It is safe to call more than once and after
+// the subscription was dropped.
+func (s *Subscription[T]) Cancel() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	if _, ok := s.broker.subs[s]; ok {
+		s.broker.drop(s)
+	}
+}
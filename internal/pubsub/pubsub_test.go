@@ -0,0 +1,114 @@
+package pubsub
+
+import "testing"
+
+func receive(t *testing.T, sub *Subscription[string]) []Event[string] {
+	t.Helper()
+	var got []Event[string]
+	for {
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				return got
+			}
+			got = append(got, e)
+		default:
+			return got
+		}
+	}
+}
+
+func TestPublish(t *testing.T) {
+	b := New[string](10, 10)
+	a, _ := b.Subscribe(0)
+	c, _ := b.Subscribe(0)
+
+	b.Publish("one")
+	b.Publish("two")
+
+	for _, sub := range []*Subscription[string]{a, c} {
+		got := receive(t, sub)
+		if len(got) != 2 || got[0] != (Event[string]{1, "one"}) || got[1] != (Event[string]{2, "two"}) {
+			t.Errorf("received %v, want events 1 and 2", got)
+		}
+	}
+}
+
+func TestSlowSubscriberDropped(t *testing.T) {
+	b := New[string](10, 2)
+	slow, _ := b.Subscribe(0)
+
+	b.Publish("a")
+	b.Publish("b")
+	b.Publish("c")
+
+	if b.Len() != 0 {
+		t.Errorf("Len() = %d, want 0 after overflow", b.Len())
+	}
+	got := receive(t, slow)
+	if len(got) != 2 {
+		t.Errorf("received %d events, want the 2 that fit", len(got))
+	}
+	if _, ok := <-slow.C; ok {
+		t.Error("channel still open after drop")
+	}
+	slow.Cancel()
+}
+
+func TestSubscribeResume(t *testing.T) {
+	b := New[string](3, 10)
+	for _, v := range []string{"a", "b", "c", "d", "e"} {
+		b.Publish(v)
+	}
+
+	tests := []struct {
+		name         string
+		lastID       uint64
+		wantIDs      []uint64
+		wantComplete bool
+	}{
+		{name: "Fresh", lastID: 0, wantIDs: nil, wantComplete: true},
+		{name: "Up to date", lastID: 5, wantIDs: nil, wantComplete: true},
+		{name: "In history", lastID: 3, wantIDs: []uint64{4, 5}, wantComplete: true},
+		{name: "Oldest edge", lastID: 2, wantIDs: []uint64{3, 4, 5}, wantComplete: true},
+		{name: "Forgotten", lastID: 1, wantIDs: []uint64{3, 4, 5}, wantComplete: false},
+		{name: "From the future", lastID: 9, wantIDs: nil, wantComplete: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, complete := b.Subscribe(tt.lastID)
+			defer sub.Cancel()
+
+			if complete != tt.wantComplete {
+				t.Errorf("complete = %v, want %v", complete, tt.wantComplete)
+			}
+			got := receive(t, sub)
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("received %v, want IDs %v", got, tt.wantIDs)
+			}
+			for i, e := range got {
+				if e.ID != tt.wantIDs[i] {
+					t.Errorf("event %d has ID %d, want %d", i, e.ID, tt.wantIDs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestClose(t *testing.T) {
+	b := New[string](1, 1)
+	sub, _ := b.Subscribe(0)
+	b.Close()
+
+	if _, ok := <-sub.C; ok {
+		t.Error("subscription open after Close")
+	}
+	sub.Cancel()
+
+	late, _ := b.Subscribe(0)
+	if _, ok := <-late.C; ok {
+		t.Error("subscription after Close is open")
+	}
+	b.Publish("ignored")
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// Error codes are part of the API contract: clients switch on them, so once
+// published they must not change meaning.
+const (
+	errCodeInvalidRequest     = "invalid_request"
+	errCodeInvalidID          = "invalid_id"
+	errCodeInvalidCursor      = "invalid_cursor"
+	errCodeChirpEmpty         = "chirp_empty"
+	errCodeChirpTooLong       = "chirp_too_long"
+	errCodeChirpProhibited    = "chirp_prohibited"
+	errCodeMissingToken       = "missing_token"
+	errCodeInvalidToken       = "invalid_token"
+	errCodeRefreshTokenReused = "refresh_token_reused"
+	errCodeInvalidCredentials = "invalid_credentials"
+	errCodeAccountSuspended   = "account_suspended"
+	errCodeAccountBanned      = "account_banned"
+	errCodeEmailTaken         = "email_taken"
+	errCodeAlreadyReported    = "already_reported"
+	errCodeInvalidTransition  = "invalid_transition"
+	errCodeMediaTooLarge      = "media_too_large"
+	errCodeUnsupportedMedia   = "unsupported_media_type"
+	errCodeInvalidAPIKey      = "invalid_api_key"
+	errCodeForbidden          = "forbidden"
+	errCodeNotFound           = "not_found"
+	errCodeInternal           = "internal_error"
+)
+
+type errorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type errorEnvelope struct {
+	Error errorBody `json:"error"`
+}
+
+func respondWithError(w http.ResponseWriter, status int, code, msg string) {
+	respondWithJSON(w, status, errorEnvelope{Error: errorBody{Code: code, Message: msg}})
+}
+
+func respondWithJSON(w http.ResponseWriter, status int, payload interface{}) {
+	dat, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error marshalling JSON %s", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":{"code":"internal_error","message":"Could not encode response"}}`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err := w.Write(dat); err != nil {
+		log.Printf("Error writing response %s", err)
+	}
+}
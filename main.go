@@ -1,73 +1,209 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"sync/atomic"
+	"time"
 
+	"github.com/Lockenrocky/chirpy/internal/auth"
+	"github.com/Lockenrocky/chirpy/internal/config"
 	"github.com/Lockenrocky/chirpy/internal/database"
-	"github.com/joho/godotenv"
+	"github.com/Lockenrocky/chirpy/internal/media"
+	"github.com/Lockenrocky/chirpy/internal/profanity"
+	"github.com/Lockenrocky/chirpy/internal/pubsub"
 	_ "github.com/lib/pq"
 )
 
 type apiConfig struct {
-	fileserverHits atomic.Int32
-	db             *database.Queries
-	platform       string
-	secret         string
-	apiKey         string
+	fileserverHits  atomic.Int32
+	db              *database.Queries
+	dbConn          *sql.DB
+	platform        string
+	jwtKeys         *auth.Keyring
+	apiKey          string
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+	undeleteWindow  time.Duration
+	chirpRetention  time.Duration
+	profanity       *profanity.Filter
+	media           media.Storage
+	chirpEvents     *pubsub.Broker[chirpEvent]
+
+	notificationEvents *pubsub.Broker[notificationEvent]
 }
 
 func main() {
 	const filepathRoot = "."
-	const port = "8080"
 
-	godotenv.Load()
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to an optional YAML config file")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	makeAdmin := flag.String("make-admin", "", "give the user with this email the admin role and exit")
+	flag.Parse()
 
-	dbUrl := os.Getenv("DB_URL")
-	if dbUrl == "" {
-		log.Fatal("DB_URL must be set")
+	conf, err := config.Load(*configPath)
+	if *printConfig {
+		fmt.Print(conf.Redacted())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid configuration:\n%s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%s", err)
 	}
-	dbConn, err := sql.Open("postgres", dbUrl)
+
+	dbConn, err := sql.Open("postgres", conf.DBURL)
 	if err != nil {
 		log.Fatalf("Error opening databse: %s", err)
 	}
 	dbQueries := database.New(dbConn)
 
+	if *makeAdmin != "" {
+		n, err := dbQueries.SetUserRoleByEmail(context.Background(), database.SetUserRoleByEmailParams{
+			Email: *makeAdmin,
+			Role:  string(auth.RoleAdmin),
+		})
+		if err != nil {
+			log.Fatalf("Error setting role: %s", err)
+		}
+		if n == 0 {
+			log.Fatalf("No user with email %s", *makeAdmin)
+		}
+		fmt.Printf("%s is now an admin\n", *makeAdmin)
+		return
+	}
+
+	profanityFilter, err := profanity.New(context.Background(), profanityLoader(dbQueries, conf.ProfanityFile))
+	if err != nil {
+		log.Fatalf("Error loading profanity list: %s", err)
+	}
+
+	mediaStorage, err := media.NewLocal(conf.MediaDir)
+	if err != nil {
+		log.Fatalf("Error opening media storage: %s", err)
+	}
+
+	jwtKeys := auth.NewHMACKeyring(conf.Secret)
+	if conf.JWTKeyFile != "" {
+		jwtKeys, err = auth.LoadKeyring(conf.JWTKeyFile, conf.JWTPreviousKeyFiles...)
+		if err != nil {
+			log.Fatalf("Error loading JWT keys: %s", err)
+		}
+		log.Printf("Signing access tokens with key %s", jwtKeys.CurrentKeyID())
+		if conf.Secret != "" {
+			jwtKeys.AcceptHMAC(conf.Secret)
+			log.Printf("Still accepting access tokens signed with SECRET; unset it once they have expired")
+		}
+	}
+
 	apiCfg := apiConfig{
-		fileserverHits: atomic.Int32{},
-		db:             dbQueries,
-		platform:       os.Getenv("PLATFORM"),
-		secret:         os.Getenv("SECRET"),
-		apiKey:         os.Getenv("POLKA_KEY"),
+		fileserverHits:  atomic.Int32{},
+		db:              dbQueries,
+		dbConn:          dbConn,
+		platform:        conf.Platform,
+		jwtKeys:         jwtKeys,
+		apiKey:          conf.PolkaKey,
+		accessTokenTTL:  conf.AccessTokenTTL,
+		refreshTokenTTL: conf.RefreshTokenTTL,
+		undeleteWindow:  conf.UndeleteWindow,
+		chirpRetention:  conf.ChirpRetention,
+		profanity:       profanityFilter,
+		media:           mediaStorage,
+		chirpEvents:     newChirpEvents(),
+
+		notificationEvents: newNotificationEvents(),
+	}
+	apiCfg.reloadProfanityOnSIGHUP()
+
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	go apiCfg.runChirpPurge(purgeCtx)
+
+	mux := apiCfg.routes(filepathRoot)
+
+	ser := newServer(conf.Addr, middlewareRecover(mux))
+	// Shutdown does not wait for streams to finish on their own; ending the
+	// subscriptions lets them return.
+	ser.RegisterOnShutdown(apiCfg.chirpEvents.Close)
+	ser.RegisterOnShutdown(apiCfg.notificationEvents.Close)
+	ser.RegisterOnShutdown(stopPurge)
+	err = serve(ser, conf.ShutdownTimeout)
+	if closeErr := dbConn.Close(); closeErr != nil {
+		log.Printf("Error closing database: %s", closeErr)
 	}
+	if err != nil {
+		log.Fatalf("Server error: %s", err)
+	}
+	log.Print("Server stopped")
+}
 
+func (cfg *apiConfig) routes(filepathRoot string) *http.ServeMux {
 	mux := http.NewServeMux()
-	fsHandler := apiCfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(filepathRoot))))
+	fsHandler := cfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(filepathRoot))))
 	mux.Handle("/app/", fsHandler)
 
 	mux.HandleFunc("GET /api/healthz", handlerReadiness)
-	mux.HandleFunc("POST /api/validate_chirp", handlerValidation)
-	mux.HandleFunc("POST /api/users", apiCfg.handlerCreateUser)
-	mux.HandleFunc("PUT /api/users", apiCfg.handleUserUpdate)
-	mux.HandleFunc("POST /api/login", apiCfg.handlerLogin)
-	mux.HandleFunc("POST /api/chirps", apiCfg.handlerCreateChirp)
-	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.handleDeleteChirp)
-	mux.HandleFunc("POST /api/refresh", apiCfg.handlerRefresh)
-	mux.HandleFunc("POST /api/revoke", apiCfg.handlerRevoke)
-	mux.HandleFunc("GET /api/chirps", apiCfg.handlerGetChirps)
-	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.handlerGetChirp)
-	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.handlePolkaWebhooks)
-
-	mux.HandleFunc("GET /admin/metrics", apiCfg.handleMetrics)
-	mux.HandleFunc("POST /admin/reset", apiCfg.handlerReset)
-
-	ser := &http.Server{
-		Addr:    ":" + port,
-		Handler: mux,
-	}
+	mux.HandleFunc("POST /api/validate_chirp", cfg.handlerValidation)
+	mux.HandleFunc("POST /api/users", cfg.handlerCreateUser)
+	mux.HandleFunc("PUT /api/users", cfg.handleUserUpdate)
+	mux.HandleFunc("POST /api/login", cfg.handlerLogin)
+	mux.HandleFunc("GET /.well-known/jwks.json", cfg.handlerJWKS)
+	mux.HandleFunc("POST /api/chirps", cfg.handlerCreateChirp)
+	mux.HandleFunc("POST /api/media", cfg.handlerUploadMedia)
+	mux.HandleFunc("GET /media/{key}", cfg.handlerServeMedia)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}", cfg.handleDeleteChirp)
+	mux.HandleFunc("PUT /api/chirps/{chirpID}", cfg.handleUpdateChirp)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/restore", cfg.handlerRestoreChirp)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/reports", cfg.handlerReportChirp)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/revisions", cfg.handlerGetChirpRevisions)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/replies", cfg.handlerGetReplies)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", cfg.handlerGetThread)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/likes", cfg.handlerLikeChirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/likes", cfg.handlerUnlikeChirp)
+	mux.HandleFunc("POST /api/users/{userID}/follow", cfg.handlerFollowUser)
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", cfg.handlerUnfollowUser)
+	mux.HandleFunc("GET /api/users/{userID}/followers", cfg.handlerGetFollowers)
+	mux.HandleFunc("GET /api/users/{userID}/following", cfg.handlerGetFollowing)
+	mux.HandleFunc("POST /api/users/{userID}/reports", cfg.handlerReportUser)
+	mux.HandleFunc("GET /api/timeline", cfg.handlerGetTimeline)
+	mux.HandleFunc("GET /api/hashtags/trending", cfg.handlerTrendingHashtags)
+	mux.HandleFunc("GET /api/hashtags/{tag}/chirps", cfg.handlerGetHashtagChirps)
+	mux.HandleFunc("GET /api/notifications", cfg.handlerGetNotifications)
+	mux.HandleFunc("POST /api/notifications/read", cfg.handlerMarkAllNotificationsRead)
+	mux.HandleFunc("POST /api/notifications/{notificationID}/read", cfg.handlerMarkNotificationRead)
+	mux.HandleFunc("GET /api/ws", cfg.handlerWebSocket)
+	mux.HandleFunc("POST /api/refresh", cfg.handlerRefresh)
+	mux.HandleFunc("POST /api/revoke", cfg.handlerRevoke)
+	mux.HandleFunc("GET /api/sessions", cfg.handlerGetSessions)
+	mux.HandleFunc("DELETE /api/sessions", cfg.handlerRevokeAllSessions)
+	mux.HandleFunc("DELETE /api/sessions/{sessionID}", cfg.handlerRevokeSession)
+	mux.HandleFunc("GET /api/chirps", cfg.handlerGetChirps)
+	mux.HandleFunc("GET /api/chirps/stream", cfg.handlerChirpStream)
+	mux.HandleFunc("GET /api/chirps/search", cfg.handlerSearchChirps)
+	mux.HandleFunc("GET /api/chirps/{chirpID}", cfg.handlerGetChirp)
+	mux.HandleFunc("POST /api/polka/webhooks", cfg.handlePolkaWebhooks)
+
+	admin := func(h http.HandlerFunc) http.Handler { return cfg.middlewareRequireRole(auth.RoleAdmin, h) }
+	moderator := func(h http.HandlerFunc) http.Handler { return cfg.middlewareRequireRole(auth.RoleModerator, h) }
+
+	mux.Handle("GET /admin/metrics", admin(cfg.handleMetrics))
+	mux.Handle("POST /admin/reset", admin(cfg.handlerReset))
+	mux.Handle("POST /admin/profanity/reload", admin(cfg.handlerReloadProfanity))
+	mux.Handle("PUT /admin/users/{userID}/role", admin(cfg.handlerSetUserRole))
+	mux.Handle("PUT /admin/users/{userID}/status", admin(cfg.handlerSetUserStatus))
+	mux.Handle("GET /admin/chirps/deleted", moderator(cfg.handlerGetDeletedChirps))
+	mux.Handle("POST /admin/chirps/purge", admin(cfg.handlerPurgeDeletedChirps))
+	mux.Handle("GET /admin/chirps/{chirpID}", moderator(cfg.handlerAdminGetChirp))
+	mux.Handle("GET /admin/reports", moderator(cfg.handlerGetReports))
+	mux.Handle("GET /admin/reports/{reportID}", moderator(cfg.handlerGetReport))
+	mux.Handle("POST /admin/reports/{reportID}/actions", moderator(cfg.handlerReportAction))
+	mux.Handle("GET /admin/moderation/actions", moderator(cfg.handlerGetModerationActions))
 
-	ser.ListenAndServe()
+	return mux
 }
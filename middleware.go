@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/Lockenrocky/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+const requestIDHeader = "X-Request-Id"
+
+type contextKey int
+
+const accessClaimsKey contextKey = iota
+
+// middlewareRecover turns a panic in any handler into a 500 response instead
+// of killing the connection. Every response carries a request ID so a client
+// report can be matched to the logged stack trace.
+func middlewareRecover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := uuid.NewString()
+		w.Header().Set(requestIDHeader, requestID)
+
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			log.Printf("panic serving %s %s (request %s): %v\n%s", r.Method, r.URL.Path, requestID, rec, debug.Stack())
+			respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Internal server error (request "+requestID+")")
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// middlewareRequireRole only lets requests through whose access token carries
+// role or a role that includes it. A missing or invalid token gets a 401; a
+// token with too little access, or one for a suspended or banned account, a
+// 403. next can get the caller's claims from accessClaimsFrom.
+func (cfg *apiConfig) middlewareRequireRole(role auth.Role, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accessToken, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, errCodeMissingToken, "Could not find access token")
+			return
+		}
+
+		claims, err := auth.ParseJWT(accessToken, cfg.jwtKeys)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, errCodeInvalidToken, "Invalid access token")
+			return
+		}
+		if !cfg.checkAccount(w, r, claims.UserID) {
+			return
+		}
+		if !claims.Role.Includes(role) {
+			respondWithError(w, http.StatusForbidden, errCodeForbidden, "This requires the "+string(role)+" role")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), accessClaimsKey, claims)))
+	})
+}
+
+// accessClaimsFrom returns the claims middlewareRequireRole stored in ctx.
+func accessClaimsFrom(ctx context.Context) (auth.AccessClaims, bool) {
+	claims, ok := ctx.Value(accessClaimsKey).(auth.AccessClaims)
+	return claims, ok
+}
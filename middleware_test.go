@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Lockenrocky/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+func TestMiddlewareRecover(t *testing.T) {
+	handler := middlewareRecover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var chirps map[string]string
+		chirps["boom"] = "assignment to nil map"
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/chirps", nil))
+
+	requestID := rec.Header().Get(requestIDHeader)
+	if requestID == "" {
+		t.Fatal("response has no request ID")
+	}
+	if !strings.Contains(rec.Body.String(), requestID) {
+		t.Errorf("body %q does not mention request ID %q", rec.Body.String(), requestID)
+	}
+	assertErrorEnvelope(t, rec, http.StatusInternalServerError, errCodeInternal)
+}
+
+func TestMiddlewareRecoverPassesThrough(t *testing.T) {
+	handler := middlewareRecover(http.HandlerFunc(handlerReadiness))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/healthz", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if rec.Header().Get(requestIDHeader) == "" {
+		t.Error("response has no request ID")
+	}
+}
+
+func TestMiddlewareRecoverRepanicsAbort(t *testing.T) {
+	handler := middlewareRecover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", rec)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestMiddlewareRequireRole(t *testing.T) {
+	cfg := newTestConfig(t)
+	adminID := uuid.New()
+	var got auth.AccessClaims
+	handler := cfg.middlewareRequireRole(auth.RoleModerator, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = accessClaimsFrom(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/chirps/deleted", nil)
+	req.Header.Set("Authorization", testBearer(t, adminID, auth.RoleAdmin))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if got.UserID != adminID || got.Role != auth.RoleAdmin {
+		t.Errorf("claims = %+v, want admin %s", got, adminID)
+	}
+}
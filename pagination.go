@@ -0,0 +1,179 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lockenrocky/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// chirpCursor is the position of the last chirp on a page. Pages are keyed on
+// (created_at, id) so chirps sharing a timestamp are never skipped or repeated;
+// LikeCount is carried as well so the same cursor works for sort=likes.
+type chirpCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+	LikeCount sql.NullInt32
+}
+
+func encodeCursor(c chirpCursor) string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	if c.LikeCount.Valid {
+		raw += "|" + strconv.Itoa(int(c.LikeCount.Int32))
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (chirpCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return chirpCursor{}, errors.New("malformed cursor")
+	}
+
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 2 && len(parts) != 3 {
+		return chirpCursor{}, errors.New("malformed cursor")
+	}
+	createdAt, id := parts[0], parts[1]
+
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return chirpCursor{}, errors.New("malformed cursor")
+	}
+
+	chirpID, err := uuid.Parse(id)
+	if err != nil {
+		return chirpCursor{}, errors.New("malformed cursor")
+	}
+
+	cursor := chirpCursor{CreatedAt: t, ID: chirpID}
+	if len(parts) == 3 {
+		likes, err := strconv.ParseInt(parts[2], 10, 32)
+		if err != nil {
+			return chirpCursor{}, errors.New("malformed cursor")
+		}
+		cursor.LikeCount = sql.NullInt32{Int32: int32(likes), Valid: true}
+	}
+	return cursor, nil
+}
+
+// encodeOffsetCursor and decodeOffsetCursor are for listings ordered by a
+// computed score, such as search rank, where there is no stable key to resume
+// from.
+func encodeOffsetCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodeOffsetCursor(s string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, errors.New("malformed cursor")
+	}
+	offset, err := strconv.Atoi(string(raw))
+	if err != nil || offset < 0 {
+		return 0, errors.New("malformed cursor")
+	}
+	return offset, nil
+}
+
+func parsePageSize(s string) (int, error) {
+	if s == "" {
+		return defaultPageSize, nil
+	}
+
+	limit, err := strconv.Atoi(s)
+	if err != nil || limit < 1 {
+		return 0, errors.New("limit must be a positive integer")
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	return limit, nil
+}
+
+// pageParams are the limit and cursor query parameters shared by every
+// paginated chirp listing.
+type pageParams struct {
+	limit           int
+	cursorTime      sql.NullTime
+	cursorID        uuid.NullUUID
+	cursorLikeCount sql.NullInt32
+}
+
+// parsePageParams reads limit and cursor from the query string. It writes a
+// 400 and returns false if either is malformed.
+func parsePageParams(w http.ResponseWriter, r *http.Request) (pageParams, bool) {
+	limit, err := parsePageSize(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "limit must be a positive integer")
+		return pageParams{}, false
+	}
+
+	params := pageParams{limit: limit}
+	if c := r.URL.Query().Get("cursor"); c != "" {
+		cursor, err := decodeCursor(c)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, errCodeInvalidCursor, "Invalid cursor")
+			return pageParams{}, false
+		}
+		params.cursorTime = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		params.cursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+		params.cursorLikeCount = cursor.LikeCount
+	}
+	return params, true
+}
+
+type chirpPage struct {
+	Chirps     []resp `json:"chirps"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+func newChirpPage(chirps []database.Chirp, nextCursor string) chirpPage {
+	page := chirpPage{Chirps: []resp{}, NextCursor: nextCursor}
+	for _, chirp := range chirps {
+		page.Chirps = append(page.Chirps, chirpToResp(chirp))
+	}
+	return page
+}
+
+// respondWithChirpPage annotates the chirps on the page and writes it.
+func (cfg *apiConfig) respondWithChirpPage(w http.ResponseWriter, r *http.Request, page chirpPage) {
+	chirps := make([]*resp, len(page.Chirps))
+	for i := range page.Chirps {
+		chirps[i] = &page.Chirps[i]
+	}
+	if err := cfg.annotateChirps(r, chirps); err != nil {
+		log.Printf("Error annotating chirps: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not get chirps")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+// pageChirps trims a result fetched with limit+1 rows down to limit and
+// returns the cursor for the next page, or "" if this is the last page.
+func pageChirps(chirps []database.Chirp, limit int) ([]database.Chirp, string) {
+	if len(chirps) <= limit {
+		return chirps, ""
+	}
+
+	chirps = chirps[:limit]
+	last := chirps[len(chirps)-1]
+	return chirps, encodeCursor(chirpCursor{
+		CreatedAt: last.CreatedAt,
+		ID:        last.ID,
+		LikeCount: sql.NullInt32{Int32: last.LikeCount, Valid: true},
+	})
+}
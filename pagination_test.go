@@ -0,0 +1,141 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/Lockenrocky/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	want := chirpCursor{
+		CreatedAt: time.Date(2025, 4, 1, 12, 30, 0, 123456000, time.UTC),
+		ID:        uuid.New(),
+		LikeCount: sql.NullInt32{Int32: 42, Valid: true},
+	}
+
+	got, err := decodeCursor(encodeCursor(want))
+	if err != nil {
+		t.Fatalf("decodeCursor() error = %v", err)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) || got.ID != want.ID || got.LikeCount != want.LikeCount {
+		t.Errorf("decodeCursor() = %v, want %v", got, want)
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "Not base64", cursor: "!!!"},
+		{name: "Missing separator", cursor: "bm9zZXBhcmF0b3I"},
+		{name: "Bad timestamp", cursor: base64.RawURLEncoding.EncodeToString([]byte("yesterday|" + uuid.NewString()))},
+		{name: "Bad id", cursor: base64.RawURLEncoding.EncodeToString([]byte("2025-04-01T12:30:00Z|not-a-uuid"))},
+		{name: "Bad like count", cursor: base64.RawURLEncoding.EncodeToString([]byte("2025-04-01T12:30:00Z|" + uuid.NewString() + "|many"))},
+		{name: "Too many parts", cursor: base64.RawURLEncoding.EncodeToString([]byte("2025-04-01T12:30:00Z|" + uuid.NewString() + "|1|2"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCursor(tt.cursor); err == nil {
+				t.Errorf("decodeCursor(%q) expected error", tt.cursor)
+			}
+		})
+	}
+}
+
+func TestOffsetCursor(t *testing.T) {
+	got, err := decodeOffsetCursor(encodeOffsetCursor(40))
+	if err != nil || got != 40 {
+		t.Errorf("decodeOffsetCursor() = %d, %v, want 40", got, err)
+	}
+
+	for _, cursor := range []string{"!!!", base64.RawURLEncoding.EncodeToString([]byte("-1")), base64.RawURLEncoding.EncodeToString([]byte("ten"))} {
+		if _, err := decodeOffsetCursor(cursor); err == nil {
+			t.Errorf("decodeOffsetCursor(%q) expected error", cursor)
+		}
+	}
+}
+
+func TestParsePageSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{name: "Default", input: "", want: defaultPageSize},
+		{name: "Explicit", input: "5", want: 5},
+		{name: "Clamped", input: "1000", want: maxPageSize},
+		{name: "Zero", input: "0", wantErr: true},
+		{name: "Negative", input: "-3", wantErr: true},
+		{name: "Not a number", input: "ten", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePageSize(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePageSize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parsePageSize() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPageChirpsBoundaries(t *testing.T) {
+	base := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	makeChirps := func(n int) []database.Chirp {
+		chirps := make([]database.Chirp, n)
+		for i := range chirps {
+			chirps[i] = database.Chirp{ID: uuid.New(), CreatedAt: base.Add(time.Duration(i) * time.Second), LikeCount: int32(10 - i)}
+		}
+		return chirps
+	}
+
+	tests := []struct {
+		name      string
+		rows      int
+		limit     int
+		wantLen   int
+		wantNext  bool
+		lastIndex int
+	}{
+		{name: "Empty", rows: 0, limit: 3, wantLen: 0},
+		{name: "Fewer than limit", rows: 2, limit: 3, wantLen: 2},
+		{name: "Exactly limit", rows: 3, limit: 3, wantLen: 3},
+		{name: "One more than limit", rows: 4, limit: 3, wantLen: 3, wantNext: true, lastIndex: 2},
+		{name: "Limit of one", rows: 2, limit: 1, wantLen: 1, wantNext: true, lastIndex: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := makeChirps(tt.rows)
+			got, next := pageChirps(rows, tt.limit)
+			if len(got) != tt.wantLen {
+				t.Fatalf("pageChirps() returned %d chirps, want %d", len(got), tt.wantLen)
+			}
+			if (next != "") != tt.wantNext {
+				t.Fatalf("pageChirps() next cursor = %q, wantNext %v", next, tt.wantNext)
+			}
+			if !tt.wantNext {
+				return
+			}
+
+			cursor, err := decodeCursor(next)
+			if err != nil {
+				t.Fatalf("decodeCursor() error = %v", err)
+			}
+			last := rows[tt.lastIndex]
+			if cursor.ID != last.ID || !cursor.CreatedAt.Equal(last.CreatedAt) || cursor.LikeCount.Int32 != last.LikeCount {
+				t.Errorf("next cursor = %v, want position of chirp %d", cursor, tt.lastIndex)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Lockenrocky/chirpy/internal/database"
+	"github.com/Lockenrocky/chirpy/internal/profanity"
+)
+
+func profanityLoader(db *database.Queries, path string) profanity.Loader {
+	if path != "" {
+		return profanity.FileLoader(path)
+	}
+	return func(ctx context.Context) ([]profanity.Rule, error) {
+		words, err := db.ListProfanityWords(ctx)
+		if err != nil {
+			return nil, err
+		}
+		rules := make([]profanity.Rule, 0, len(words))
+		for _, w := range words {
+			rules = append(rules, profanity.Rule{Word: w.Word, Strategy: profanity.Strategy(w.Strategy)})
+		}
+		return rules, nil
+	}
+}
+
+// reloadProfanityOnSIGHUP reloads the word list every time the process
+// receives SIGHUP, so the list can be changed without a restart.
+func (cfg *apiConfig) reloadProfanityOnSIGHUP() {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	go func() {
+		for range sighup {
+			if err := cfg.profanity.Reload(context.Background()); err != nil {
+				log.Printf("Error reloading profanity list: %s", err)
+				continue
+			}
+			log.Printf("Reloaded profanity list with %d words", cfg.profanity.Len())
+		}
+	}()
+}
+
+func (cfg *apiConfig) handlerReloadProfanity(w http.ResponseWriter, r *http.Request) {
+	if err := cfg.profanity.Reload(r.Context()); err != nil {
+		log.Printf("Error reloading profanity list: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not reload profanity list")
+		return
+	}
+
+	type resp struct {
+		Words int `json:"words"`
+	}
+	respondWithJSON(w, http.StatusOK, resp{Words: cfg.profanity.Len()})
+}
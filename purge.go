@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const chirpPurgeInterval = time.Hour
+
+// unattachedMediaTTL is how long an upload may wait to be attached to a chirp
+// before it is purged.
+const unattachedMediaTTL = 24 * time.Hour
+
+// runChirpPurge calls purgeDeletedChirps and purgeUnattachedMedia straight
+// away and then every chirpPurgeInterval until ctx is cancelled.
+func (cfg *apiConfig) runChirpPurge(ctx context.Context) {
+	ticker := time.NewTicker(chirpPurgeInterval)
+	defer ticker.Stop()
+	for {
+		n, err := cfg.purgeDeletedChirps(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Error purging deleted chirps: %s", err)
+		} else if n > 0 {
+			log.Printf("Purged %d deleted chirps", n)
+		}
+		n, err = cfg.purgeUnattachedMedia(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Error purging unattached media: %s", err)
+		} else if n > 0 {
+			log.Printf("Purged %d unattached uploads", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeDeletedChirps permanently removes chirps that were deleted more than
+// cfg.chirpRetention ago, along with their media files, and returns how many
+// chirps it removed.
+func (cfg *apiConfig) purgeDeletedChirps(ctx context.Context) (int, error) {
+	cutoff := sql.NullTime{Time: time.Now().UTC().Add(-cfg.chirpRetention), Valid: true}
+	rows, err := cfg.db.PurgeDeletedChirps(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := map[uuid.UUID]bool{}
+	for _, row := range rows {
+		purged[row.ID] = true
+		if row.FileKey.Valid {
+			cfg.deleteMediaFiles(ctx, row.FileKey.String, row.ThumbnailKey.String)
+		}
+	}
+	return len(purged), nil
+}
+
+// purgeUnattachedMedia removes uploads that have not been attached to a chirp
+// within unattachedMediaTTL, along with their files, and returns how many it
+// removed.
+func (cfg *apiConfig) purgeUnattachedMedia(ctx context.Context) (int, error) {
+	rows, err := cfg.db.PurgeUnattachedMedia(ctx, time.Now().UTC().Add(-unattachedMediaTTL))
+	if err != nil {
+		return 0, err
+	}
+	for _, row := range rows {
+		cfg.deleteMediaFiles(ctx, row.FileKey, row.ThumbnailKey)
+	}
+	return len(rows), nil
+}
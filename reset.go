@@ -4,12 +4,17 @@ import "net/http"
 
 func (cfg *apiConfig) handlerReset(w http.ResponseWriter, r *http.Request) {
 	if cfg.platform != "dev" {
-		w.WriteHeader(http.StatusForbidden)
-		w.Write([]byte("Reset is only allowed in dev environment."))
+		respondWithError(w, http.StatusForbidden, errCodeForbidden, "Reset is only allowed in dev environment")
 		return
 	}
 	cfg.fileserverHits.Store(0)
-	cfg.db.DeleteUsers(r.Context())
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Hits reset to 0"))
+	if err := cfg.db.DeleteUsers(r.Context()); err != nil {
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not delete users")
+		return
+	}
+
+	type response struct {
+		Hits int32 `json:"hits"`
+	}
+	respondWithJSON(w, http.StatusOK, response{Hits: cfg.fileserverHits.Load()})
 }
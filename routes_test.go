@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lockenrocky/chirpy/internal/auth"
+	"github.com/Lockenrocky/chirpy/internal/database"
+	"github.com/Lockenrocky/chirpy/internal/media"
+	"github.com/Lockenrocky/chirpy/internal/profanity"
+	"github.com/google/uuid"
+)
+
+// emptyDriver is a database/sql driver whose queries always return no rows
+// and whose statements always succeed, so handlers can be exercised down to
+// their "not found" paths without a running Postgres.
+type emptyDriver struct{}
+
+type emptyConn struct{}
+
+type emptyStmt struct{}
+
+type emptyRows struct{}
+
+func (emptyDriver) Open(string) (driver.Conn, error) { return emptyConn{}, nil }
+
+func (emptyConn) Prepare(string) (driver.Stmt, error) { return emptyStmt{}, nil }
+func (emptyConn) Close() error                        { return nil }
+func (emptyConn) Begin() (driver.Tx, error)           { return nil, errors.New("transactions not supported") }
+
+func (emptyStmt) Close() error                               { return nil }
+func (emptyStmt) NumInput() int                              { return -1 }
+func (emptyStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(0), nil }
+func (emptyStmt) Query([]driver.Value) (driver.Rows, error)  { return emptyRows{}, nil }
+
+func (emptyRows) Columns() []string         { return nil }
+func (emptyRows) Close() error              { return nil }
+func (emptyRows) Next([]driver.Value) error { return io.EOF }
+
+func init() {
+	sql.Register("chirpy-empty", emptyDriver{})
+}
+
+// testKeys signs the access tokens tests send. Every test config verifies
+// with it.
+var testKeys = auth.NewHMACKeyring("test-secret")
+
+const testPolkaKey = "test-polka-key"
+
+func newTestConfig(t *testing.T) *apiConfig {
+	t.Helper()
+	conn, err := sql.Open("chirpy-empty", "")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	mediaStorage, err := media.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("media.NewLocal() error = %v", err)
+	}
+
+	return &apiConfig{
+		db:              database.New(conn),
+		dbConn:          conn,
+		platform:        "prod",
+		jwtKeys:         testKeys,
+		apiKey:          testPolkaKey,
+		accessTokenTTL:  time.Hour,
+		refreshTokenTTL: time.Hour,
+		profanity:       newTestProfanityFilter(t),
+		media:           mediaStorage,
+		chirpEvents:     newChirpEvents(),
+
+		notificationEvents: newNotificationEvents(),
+	}
+}
+
+// testBearer returns an Authorization header value for an access token.
+func testBearer(t *testing.T, userID uuid.UUID, role auth.Role) string {
+	t.Helper()
+	token, err := auth.MakeJWT(userID, role, testKeys, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+	return "Bearer " + token
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	userID := uuid.New()
+	bearer := testBearer(t, userID, auth.RoleUser)
+	moderatorBearer := testBearer(t, uuid.New(), auth.RoleModerator)
+	adminID := uuid.New()
+	adminBearer := testBearer(t, adminID, auth.RoleAdmin)
+	chirpPath := "/api/chirps/" + uuid.NewString()
+	userPath := "/api/users/" + uuid.NewString()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		auth       string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{"validate bad JSON", "POST", "/api/validate_chirp", "", `{"body":`, 400, errCodeInvalidRequest},
+		{"validate too long", "POST", "/api/validate_chirp", "", `{"body":"` + strings.Repeat("a", 141) + `"}`, 400, errCodeChirpTooLong},
+		{"create user bad JSON", "POST", "/api/users", "", `{"email":`, 400, errCodeInvalidRequest},
+		{"create user database error", "POST", "/api/users", "", `{"email":"a@b.c","password":"x"}`, 500, errCodeInternal},
+		{"update user without token", "PUT", "/api/users", "", `{}`, 401, errCodeMissingToken},
+		{"update user bad token", "PUT", "/api/users", "Bearer nope", `{}`, 401, errCodeInvalidToken},
+		{"update user bad JSON", "PUT", "/api/users", bearer, `not json`, 400, errCodeInvalidRequest},
+		{"login bad JSON", "POST", "/api/login", "", `[`, 400, errCodeInvalidRequest},
+		{"login unknown user", "POST", "/api/login", "", `{"email":"a@b.c","password":"x"}`, 401, errCodeInvalidCredentials},
+		{"create chirp bad JSON", "POST", "/api/chirps", bearer, `{"body":1}`, 400, errCodeInvalidRequest},
+		{"create chirp without token", "POST", "/api/chirps", "", `{"body":"hi"}`, 401, errCodeMissingToken},
+		{"create chirp bad token", "POST", "/api/chirps", "Bearer nope", `{"body":"hi"}`, 401, errCodeInvalidToken},
+		{"create chirp empty", "POST", "/api/chirps", bearer, `{"body":""}`, 400, errCodeChirpEmpty},
+		{"create chirp prohibited", "POST", "/api/chirps", bearer, `{"body":"fornax"}`, 400, errCodeChirpProhibited},
+		{"create chirp too many media", "POST", "/api/chirps", bearer, `{"body":"hi","media_ids":["` + strings.Repeat(uuid.NewString()+`","`, 4) + uuid.NewString() + `"]}`, 400, errCodeInvalidRequest},
+		{"create chirp duplicate media", "POST", "/api/chirps", bearer, `{"body":"hi","media_ids":["` + userID.String() + `","` + userID.String() + `"]}`, 400, errCodeInvalidRequest},
+		{"upload without token", "POST", "/api/media", "", "", 401, errCodeMissingToken},
+		{"upload not multipart", "POST", "/api/media", bearer, `{}`, 400, errCodeInvalidRequest},
+		{"media not found", "GET", "/media/" + uuid.NewString() + ".png", "", "", 404, errCodeNotFound},
+		{"media bad key", "GET", "/media/.upload-123", "", "", 404, errCodeNotFound},
+		{"delete chirp without token", "DELETE", chirpPath, "", "", 401, errCodeMissingToken},
+		{"delete chirp bad id", "DELETE", "/api/chirps/not-a-uuid", bearer, "", 404, errCodeNotFound},
+		{"delete chirp not found", "DELETE", chirpPath, bearer, "", 404, errCodeNotFound},
+		{"restore chirp without token", "POST", chirpPath + "/restore", "", "", 401, errCodeMissingToken},
+		{"restore chirp bad id", "POST", "/api/chirps/not-a-uuid/restore", bearer, "", 404, errCodeNotFound},
+		{"restore chirp not found", "POST", chirpPath + "/restore", bearer, "", 404, errCodeNotFound},
+		{"admin chirp bad id", "GET", "/admin/chirps/not-a-uuid", moderatorBearer, "", 404, errCodeNotFound},
+		{"admin chirp not found", "GET", "/admin/chirps/" + uuid.NewString(), moderatorBearer, "", 404, errCodeNotFound},
+		{"admin chirp as user", "GET", "/admin/chirps/" + uuid.NewString(), bearer, "", 403, errCodeForbidden},
+		{"deleted chirps bad cursor", "GET", "/admin/chirps/deleted?cursor=%21", moderatorBearer, "", 400, errCodeInvalidCursor},
+		{"deleted chirps without token", "GET", "/admin/chirps/deleted", "", "", 401, errCodeMissingToken},
+		{"purge as moderator", "POST", "/admin/chirps/purge", moderatorBearer, "", 403, errCodeForbidden},
+		{"report chirp without token", "POST", chirpPath + "/reports", "", `{"reason":"spam"}`, 401, errCodeMissingToken},
+		{"report chirp bad id", "POST", "/api/chirps/not-a-uuid/reports", bearer, `{"reason":"spam"}`, 404, errCodeNotFound},
+		{"report chirp not found", "POST", chirpPath + "/reports", bearer, `{"reason":"spam"}`, 404, errCodeNotFound},
+		{"report user not found", "POST", userPath + "/reports", bearer, `{"reason":"spam"}`, 404, errCodeNotFound},
+		{"reports as user", "GET", "/admin/reports", bearer, "", 403, errCodeForbidden},
+		{"reports bad status", "GET", "/admin/reports?status=closed", moderatorBearer, "", 400, errCodeInvalidRequest},
+		{"reports bad user_id", "GET", "/admin/reports?user_id=nope", moderatorBearer, "", 400, errCodeInvalidID},
+		{"report not found", "GET", "/admin/reports/" + uuid.NewString(), moderatorBearer, "", 404, errCodeNotFound},
+		{"report action unknown", "POST", "/admin/reports/" + uuid.NewString() + "/actions", moderatorBearer, `{"action":"ban"}`, 400, errCodeInvalidRequest},
+		{"report suspend without duration", "POST", "/admin/reports/" + uuid.NewString() + "/actions", moderatorBearer, `{"action":"suspend_user"}`, 400, errCodeInvalidRequest},
+		{"report action not found", "POST", "/admin/reports/" + uuid.NewString() + "/actions", moderatorBearer, `{"action":"dismiss"}`, 404, errCodeNotFound},
+		{"moderation actions bad report_id", "GET", "/admin/moderation/actions?report_id=nope", moderatorBearer, "", 400, errCodeInvalidID},
+		{"edit chirp without token", "PUT", chirpPath, "", `{"body":"fixed"}`, 401, errCodeMissingToken},
+		{"edit chirp bad id", "PUT", "/api/chirps/not-a-uuid", bearer, `{"body":"fixed"}`, 404, errCodeNotFound},
+		{"edit chirp not found", "PUT", chirpPath, bearer, `{"body":"fixed"}`, 404, errCodeNotFound},
+		{"chirp revisions bad id", "GET", "/api/chirps/not-a-uuid/revisions", "", "", 404, errCodeNotFound},
+		{"chirp revisions not found", "GET", chirpPath + "/revisions", "", "", 404, errCodeNotFound},
+		{"replies bad id", "GET", "/api/chirps/not-a-uuid/replies", "", "", 404, errCodeNotFound},
+		{"replies bad cursor", "GET", chirpPath + "/replies?cursor=%21", "", "", 400, errCodeInvalidCursor},
+		{"replies not found", "GET", chirpPath + "/replies", "", "", 404, errCodeNotFound},
+		{"thread bad depth", "GET", chirpPath + "/thread?depth=-1", "", "", 400, errCodeInvalidRequest},
+		{"thread not found", "GET", chirpPath + "/thread", "", "", 404, errCodeNotFound},
+		{"like without token", "POST", chirpPath + "/likes", "", "", 401, errCodeMissingToken},
+		{"like bad id", "POST", "/api/chirps/not-a-uuid/likes", bearer, "", 404, errCodeNotFound},
+		{"like not found", "POST", chirpPath + "/likes", bearer, "", 404, errCodeNotFound},
+		{"unlike without token", "DELETE", chirpPath + "/likes", "", "", 401, errCodeMissingToken},
+		{"unlike not found", "DELETE", chirpPath + "/likes", bearer, "", 404, errCodeNotFound},
+		{"list by likes with time-only cursor", "GET", "/api/chirps?sort=likes&cursor=" + encodeCursor(chirpCursor{CreatedAt: time.Now(), ID: uuid.New()}), "", "", 400, errCodeInvalidCursor},
+		{"follow without token", "POST", userPath + "/follow", "", "", 401, errCodeMissingToken},
+		{"follow bad id", "POST", "/api/users/not-a-uuid/follow", bearer, "", 404, errCodeNotFound},
+		{"follow self", "POST", "/api/users/" + userID.String() + "/follow", bearer, "", 400, errCodeInvalidRequest},
+		{"unfollow without token", "DELETE", userPath + "/follow", "", "", 401, errCodeMissingToken},
+		{"followers bad id", "GET", "/api/users/not-a-uuid/followers", "", "", 404, errCodeNotFound},
+		{"followers bad cursor", "GET", userPath + "/followers?cursor=%21", "", "", 400, errCodeInvalidCursor},
+		{"followers not found", "GET", userPath + "/followers", "", "", 404, errCodeNotFound},
+		{"following not found", "GET", userPath + "/following", "", "", 404, errCodeNotFound},
+		{"timeline without token", "GET", "/api/timeline", "", "", 401, errCodeMissingToken},
+		{"timeline bad limit", "GET", "/api/timeline?limit=abc", bearer, "", 400, errCodeInvalidRequest},
+		{"hashtag chirps bad cursor", "GET", "/api/hashtags/go/chirps?cursor=%21", "", "", 400, errCodeInvalidCursor},
+		{"trending bad window", "GET", "/api/hashtags/trending?window=forever", "", "", 400, errCodeInvalidRequest},
+		{"trending window too long", "GET", "/api/hashtags/trending?window=720h", "", "", 400, errCodeInvalidRequest},
+		{"notifications without token", "GET", "/api/notifications", "", "", 401, errCodeMissingToken},
+		{"notifications bad cursor", "GET", "/api/notifications?cursor=%21", bearer, "", 400, errCodeInvalidCursor},
+		{"mark notification read bad id", "POST", "/api/notifications/not-a-uuid/read", bearer, "", 404, errCodeNotFound},
+		{"mark notification read not found", "POST", "/api/notifications/" + uuid.NewString() + "/read", bearer, "", 404, errCodeNotFound},
+		{"mark all read without token", "POST", "/api/notifications/read", "", "", 401, errCodeMissingToken},
+		{"websocket without token", "GET", "/api/ws", "", "", 401, errCodeMissingToken},
+		{"websocket bad token", "GET", "/api/ws?access_token=nope", "", "", 401, errCodeInvalidToken},
+		{"refresh without token", "POST", "/api/refresh", "", "", 401, errCodeMissingToken},
+		{"refresh unknown token", "POST", "/api/refresh", "Bearer deadbeef", "", 401, errCodeInvalidToken},
+		{"revoke without token", "POST", "/api/revoke", "", "", 401, errCodeMissingToken},
+		{"login device name too long", "POST", "/api/login", "", `{"email":"a@example.com","password":"x","device_name":"` + strings.Repeat("d", 101) + `"}`, 400, errCodeInvalidRequest},
+		{"sessions without token", "GET", "/api/sessions", "", "", 401, errCodeMissingToken},
+		{"revoke session bad id", "DELETE", "/api/sessions/not-a-uuid", bearer, "", 404, errCodeNotFound},
+		{"revoke session not found", "DELETE", "/api/sessions/" + uuid.NewString(), bearer, "", 404, errCodeNotFound},
+		{"revoke all sessions without token", "DELETE", "/api/sessions", "", "", 401, errCodeMissingToken},
+		{"list chirps bad limit", "GET", "/api/chirps?limit=0", "", "", 400, errCodeInvalidRequest},
+		{"list chirps bad cursor", "GET", "/api/chirps?cursor=%21", "", "", 400, errCodeInvalidCursor},
+		{"list chirps bad author", "GET", "/api/chirps?author_id=nope", "", "", 400, errCodeInvalidID},
+		{"search without words", "GET", "/api/chirps/search?q=%21%3F", "", "", 400, errCodeInvalidRequest},
+		{"search bad cursor", "GET", "/api/chirps/search?q=fox&cursor=%21", "", "", 400, errCodeInvalidCursor},
+		{"search bad author", "GET", "/api/chirps/search?q=fox&author_id=nope", "", "", 400, errCodeInvalidID},
+		{"search bad since", "GET", "/api/chirps/search?q=fox&since=yesterday", "", "", 400, errCodeInvalidRequest},
+		{"stream bad author", "GET", "/api/chirps/stream?author_id=nope", "", "", 400, errCodeInvalidID},
+		{"stream bad last event id", "GET", "/api/chirps/stream?last_event_id=abc", "", "", 400, errCodeInvalidCursor},
+		{"get chirp bad id", "GET", "/api/chirps/not-a-uuid", "", "", 404, errCodeNotFound},
+		{"get chirp not found", "GET", chirpPath, "", "", 404, errCodeNotFound},
+		{"polka without key", "POST", "/api/polka/webhooks", "", `{}`, 401, errCodeMissingToken},
+		{"polka wrong key", "POST", "/api/polka/webhooks", "ApiKey wrong", `{}`, 401, errCodeInvalidAPIKey},
+		{"polka bad JSON", "POST", "/api/polka/webhooks", "ApiKey " + testPolkaKey, `{`, 400, errCodeInvalidRequest},
+		{"polka unknown user", "POST", "/api/polka/webhooks", "ApiKey " + testPolkaKey, `{"event":"user.upgraded","data":{"user_id":"` + uuid.NewString() + `"}}`, 404, errCodeNotFound},
+		{"reset without token", "POST", "/admin/reset", "", "", 401, errCodeMissingToken},
+		{"reset outside dev", "POST", "/admin/reset", adminBearer, "", 403, errCodeForbidden},
+		{"metrics without token", "GET", "/admin/metrics", "", "", 401, errCodeMissingToken},
+		{"metrics bad token", "GET", "/admin/metrics", "Bearer nope", "", 401, errCodeInvalidToken},
+		{"metrics as user", "GET", "/admin/metrics", bearer, "", 403, errCodeForbidden},
+		{"metrics as moderator", "GET", "/admin/metrics", moderatorBearer, "", 403, errCodeForbidden},
+		{"profanity reload as user", "POST", "/admin/profanity/reload", bearer, "", 403, errCodeForbidden},
+		{"set role as moderator", "PUT", "/admin/users/" + uuid.NewString() + "/role", moderatorBearer, `{"role":"admin"}`, 403, errCodeForbidden},
+		{"set status as moderator", "PUT", "/admin/users/" + uuid.NewString() + "/status", moderatorBearer, `{"status":"banned"}`, 403, errCodeForbidden},
+		{"set status unknown", "PUT", "/admin/users/" + uuid.NewString() + "/status", adminBearer, `{"status":"frozen"}`, 400, errCodeInvalidRequest},
+		{"set status suspended without duration", "PUT", "/admin/users/" + uuid.NewString() + "/status", adminBearer, `{"status":"suspended"}`, 400, errCodeInvalidRequest},
+		{"set status banned with duration", "PUT", "/admin/users/" + uuid.NewString() + "/status", adminBearer, `{"status":"banned","duration":"1h"}`, 400, errCodeInvalidRequest},
+		{"set own status", "PUT", "/admin/users/" + adminID.String() + "/status", adminBearer, `{"status":"active"}`, 403, errCodeForbidden},
+		{"set status not found", "PUT", "/admin/users/" + uuid.NewString() + "/status", adminBearer, `{"status":"banned"}`, 404, errCodeNotFound},
+		{"set role unknown role", "PUT", "/admin/users/" + uuid.NewString() + "/role", adminBearer, `{"role":"owner"}`, 400, errCodeInvalidRequest},
+		{"set role bad id", "PUT", "/admin/users/not-a-uuid/role", adminBearer, `{"role":"user"}`, 404, errCodeNotFound},
+		{"set own role", "PUT", "/admin/users/" + adminID.String() + "/role", adminBearer, `{"role":"user"}`, 403, errCodeForbidden},
+		{"set role user not found", "PUT", "/admin/users/" + uuid.NewString() + "/role", adminBearer, `{"role":"moderator"}`, 404, errCodeNotFound},
+	}
+
+	mux := newTestConfig(t).routes(".")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			assertErrorEnvelope(t, rec, tt.wantStatus, tt.wantCode)
+		})
+	}
+}
+
+func TestReloadProfanityErrorEnvelope(t *testing.T) {
+	cfg := newTestConfig(t)
+	fail := false
+	filter, err := profanity.New(context.Background(), func(ctx context.Context) ([]profanity.Rule, error) {
+		if fail {
+			return nil, errors.New("word list unavailable")
+		}
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("profanity.New() error = %v", err)
+	}
+	cfg.profanity = filter
+	fail = true
+
+	req := httptest.NewRequest("POST", "/admin/profanity/reload", nil)
+	req.Header.Set("Authorization", testBearer(t, uuid.New(), auth.RoleAdmin))
+	rec := httptest.NewRecorder()
+	cfg.routes(".").ServeHTTP(rec, req)
+	assertErrorEnvelope(t, rec, http.StatusInternalServerError, errCodeInternal)
+}
+
+func assertErrorEnvelope(t *testing.T, rec *httptest.ResponseRecorder, wantStatus int, wantCode string) {
+	t.Helper()
+	if rec.Code != wantStatus {
+		t.Fatalf("status = %d, want %d (body %q)", rec.Code, wantStatus, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	var envelope struct {
+		Error *struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&envelope); err != nil {
+		t.Fatalf("body is not JSON: %v", err)
+	}
+	if envelope.Error == nil {
+		t.Fatal("body has no error object")
+	}
+	if envelope.Error.Code != wantCode {
+		t.Errorf("error code = %q, want %q", envelope.Error.Code, wantCode)
+	}
+	if envelope.Error.Message == "" {
+		t.Error("error message is empty")
+	}
+}
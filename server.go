@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func newServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+}
+
+// serve runs ser until it fails or the process receives SIGINT/SIGTERM, and
+// then shuts it down as serveUntil does.
+func serve(ser *http.Server, timeout time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ln, err := net.Listen("tcp", ser.Addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("Serving on %s", ln.Addr())
+	return serveUntil(ctx, ser, ln, timeout)
+}
+
+// serveUntil runs ser on ln until it fails or ctx is done. It then stops
+// accepting connections and waits up to timeout for in-flight requests to
+// finish.
+func serveUntil(ctx context.Context, ser *http.Server, ln net.Listener, timeout time.Duration) error {
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- ser.Serve(ln)
+	}()
+
+	select {
+	case err := <-serverErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down, waiting up to %s for in-flight requests", timeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := ser.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	if err := <-serverErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
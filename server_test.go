@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startServer runs handler with serveUntil on a free local port. It returns
+// the base URL, a function that triggers shutdown, and a channel that
+// receives serveUntil's result.
+func startServer(t *testing.T, handler http.Handler, timeout time.Duration) (string, context.CancelFunc, <-chan error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	ctx, shutdown := context.WithCancel(context.Background())
+	t.Cleanup(shutdown)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- serveUntil(ctx, newServer(ln.Addr().String(), handler), ln, timeout)
+	}()
+	return "http://" + ln.Addr().String(), shutdown, done
+}
+
+func TestServeUntilFinishesInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	var finished atomic.Bool
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		finished.Store(true)
+		io.WriteString(w, "done")
+	})
+	url, shutdown, done := startServer(t, handler, 5*time.Second)
+
+	type result struct {
+		body string
+		err  error
+	}
+	resp := make(chan result, 1)
+	go func() {
+		res, err := http.Get(url)
+		if err != nil {
+			resp <- result{err: err}
+			return
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		resp <- result{string(body), err}
+	}()
+
+	<-started
+	shutdown()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("serveUntil() error = %v", err)
+		}
+		if !finished.Load() {
+			t.Error("serveUntil() returned before the in-flight request finished")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serveUntil() did not return after shutdown")
+	}
+
+	got := <-resp
+	if got.err != nil || got.body != "done" {
+		t.Errorf("in-flight request = %q, %v; want done", got.body, got.err)
+	}
+}
+
+func TestServeUntilHonoursTimeout(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+	url, shutdown, done := startServer(t, handler, 50*time.Millisecond)
+
+	go func() {
+		res, err := http.Get(url)
+		if err == nil {
+			res.Body.Close()
+		}
+	}()
+
+	<-started
+	shutdown()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("serveUntil() error = %v, want the shutdown deadline to pass", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serveUntil() waited past its timeout")
+	}
+}
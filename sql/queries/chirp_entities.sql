@@ -0,0 +1,50 @@
+-- name: InsertChirpHashtags :exec
+INSERT INTO chirp_hashtags (chirp_id, tag, created_at)
+SELECT chirps.id, tags.tag, chirps.created_at
+FROM chirps, unnest(sqlc.arg('tags')::text[]) AS tags(tag)
+WHERE chirps.id = sqlc.arg('chirp_id')
+ON CONFLICT (chirp_id, tag) DO NOTHING;
+
+-- name: InsertChirpMentions :many
+-- Handles shared by more than one user are ambiguous and are not linked.
+INSERT INTO chirp_mentions (chirp_id, user_id, handle)
+SELECT sqlc.arg('chirp_id')::uuid, users.id, handles.handle
+FROM unnest(sqlc.arg('handles')::text[]) AS handles(handle)
+JOIN users ON lower(split_part(users.email, '@', 1)) = handles.handle
+WHERE (
+    SELECT count(*) FROM users AS same
+    WHERE lower(split_part(same.email, '@', 1)) = handles.handle
+) = 1
+ON CONFLICT (chirp_id, handle) DO NOTHING
+RETURNING user_id;
+
+-- name: DeleteChirpEntities :exec
+WITH deleted_hashtags AS (
+    DELETE FROM chirp_hashtags
+    WHERE chirp_hashtags.chirp_id = $1
+)
+DELETE FROM chirp_mentions
+WHERE chirp_mentions.chirp_id = $1;
+
+-- name: SelectChirpMentions :many
+SELECT chirp_id, user_id, handle FROM chirp_mentions
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]);
+
+-- name: SelectHashtagChirpsPage :many
+SELECT chirps.* FROM chirps
+JOIN chirp_hashtags ON chirp_hashtags.chirp_id = chirps.id
+WHERE chirp_hashtags.tag = sqlc.arg('tag') AND chirps.deleted_at IS NULL
+AND (
+    sqlc.narg('before_created_at')::timestamp IS NULL
+    OR (chirp_hashtags.created_at, chirp_hashtags.chirp_id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid)
+)
+ORDER BY chirp_hashtags.created_at DESC, chirp_hashtags.chirp_id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: SelectTrendingHashtags :many
+SELECT chirp_hashtags.tag, count(*) AS chirp_count FROM chirp_hashtags
+JOIN chirps ON chirps.id = chirp_hashtags.chirp_id
+WHERE chirp_hashtags.created_at >= sqlc.arg('since') AND chirps.deleted_at IS NULL
+GROUP BY chirp_hashtags.tag
+ORDER BY chirp_count DESC, tag
+LIMIT sqlc.arg('page_size');
@@ -0,0 +1,4 @@
+-- name: SelectChirpRevisions :many
+SELECT * FROM chirp_revisions
+WHERE chirp_id = $1
+ORDER BY replaced_at DESC;
@@ -0,0 +1,17 @@
+-- name: SearchChirps :many
+-- query is to_tsquery syntax; the handler builds it from the q parameter.
+-- Matches are wrapped in U+E000/U+E001 so the handler can escape the
+-- snippet before turning them into markup.
+SELECT
+    chirps.*,
+    ts_rank_cd(to_tsvector('english', chirps.body), query)::real AS rank,
+    ts_headline('english', chirps.body, query, E'StartSel=\uE000, StopSel=\uE001, HighlightAll=true') AS snippet
+FROM chirps,
+    to_tsquery('english', sqlc.arg('query')) AS query
+WHERE to_tsvector('english', chirps.body) @@ query
+AND chirps.deleted_at IS NULL
+AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id')::uuid)
+AND (sqlc.narg('since')::timestamp IS NULL OR chirps.created_at >= sqlc.narg('since')::timestamp)
+AND (sqlc.narg('until')::timestamp IS NULL OR chirps.created_at < sqlc.narg('until')::timestamp)
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('page_size') OFFSET sqlc.arg('page_offset');
@@ -1,11 +1,16 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-VALUES (
+-- Returns no row if in_reply_to names a deleted chirp.
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to)
+SELECT
     gen_random_uuid(),
     NOW(),
     NOW(),
-    $1,
-    $2
+    sqlc.arg('body'),
+    sqlc.arg('user_id'),
+    sqlc.narg('in_reply_to')
+WHERE sqlc.narg('in_reply_to')::uuid IS NULL OR EXISTS (
+    SELECT 1 FROM chirps AS parent
+    WHERE parent.id = sqlc.narg('in_reply_to')::uuid AND parent.deleted_at IS NULL
 )
 RETURNING *;
 
@@ -14,17 +19,171 @@ DELETE FROM chirps;
 
 -- name: SelectAllChirps :many
 SELECT * FROM chirps
+WHERE deleted_at IS NULL
 ORDER BY created_at;
 
 -- name: SelectAllChirpsFromAuthor :many
 SELECT * FROM chirps
-WHERE user_id = $1
+WHERE user_id = $1 AND deleted_at IS NULL
 ORDER BY created_at;
 
 -- name: SelectChirp :one
 SELECT * FROM chirps
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: DeleteChirp :execrows
+UPDATE chirps
+SET deleted_at = NOW(), deleted_by = sqlc.arg('deleted_by')
+WHERE id = sqlc.arg('id') AND deleted_at IS NULL;
+
+-- name: SelectChirpsPageAsc :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+AND (
+    sqlc.narg('after_created_at')::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid)
+)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('page_size');
+
+-- name: SelectChirpsPageDesc :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+AND (
+    sqlc.narg('before_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: UpdateChirpBody :one
+WITH prior AS (
+    INSERT INTO chirp_revisions (id, chirp_id, body, created_at, replaced_at)
+    SELECT gen_random_uuid(), chirps.id, chirps.body, chirps.updated_at, NOW()
+    FROM chirps
+    WHERE chirps.id = $1 AND chirps.deleted_at IS NULL
+)
+UPDATE chirps
+SET body = $2, updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: SelectRepliesPage :many
+SELECT * FROM chirps
+WHERE in_reply_to = sqlc.arg('parent_id') AND deleted_at IS NULL
+AND (
+    sqlc.narg('after_created_at')::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid)
+)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('page_size');
+
+-- name: SelectThreadRoot :one
+-- A deleted chirp cuts the thread: replies to it are roots of their own.
+WITH RECURSIVE ancestors AS (
+    SELECT chirps.id, chirps.in_reply_to FROM chirps
+    WHERE chirps.id = $1 AND chirps.deleted_at IS NULL
+    UNION ALL
+    SELECT c.id, c.in_reply_to FROM chirps c
+    JOIN ancestors a ON c.id = a.in_reply_to
+    WHERE c.deleted_at IS NULL
+)
+SELECT ancestors.id FROM ancestors
+WHERE NOT EXISTS (
+    SELECT 1 FROM chirps AS parent
+    WHERE parent.id = ancestors.in_reply_to AND parent.deleted_at IS NULL
+)
+LIMIT 1;
+
+-- name: SelectThread :many
+-- Each chirp contributes at most replies_per_chirp replies, oldest first, and
+-- the thread at most max_chirps chirps. There is deliberately no ORDER BY:
+-- Postgres evaluates a recursive query level by level and stops as soon as
+-- the LIMIT is met, so a sort here would build the whole tree first.
+-- reply_count is every visible reply, so the caller can tell which chirps had
+-- replies cut off.
+WITH RECURSIVE thread AS (
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.like_count, 0 AS depth
+    FROM chirps
+    WHERE chirps.id = sqlc.arg('root_id') AND chirps.deleted_at IS NULL
+    UNION ALL
+    SELECT c.id, c.created_at, c.updated_at, c.body, c.user_id, c.in_reply_to, c.like_count, thread.depth + 1
+    FROM thread
+    CROSS JOIN LATERAL (
+        SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.like_count
+        FROM chirps
+        WHERE chirps.in_reply_to = thread.id AND chirps.deleted_at IS NULL
+        ORDER BY chirps.created_at, chirps.id
+        LIMIT sqlc.arg('replies_per_chirp')::int
+    ) AS c
+    WHERE thread.depth < sqlc.arg('max_depth')::int
+)
+SELECT thread.id, thread.created_at, thread.updated_at, thread.body, thread.user_id, thread.in_reply_to, thread.like_count, thread.depth,
+    (SELECT count(*) FROM chirps AS r WHERE r.in_reply_to = thread.id AND r.deleted_at IS NULL) AS reply_count
+FROM thread
+LIMIT sqlc.arg('max_chirps');
+
+-- name: SelectChirpsPageLikes :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+AND (
+    sqlc.narg('before_like_count')::int IS NULL
+    OR (like_count, created_at, id) < (sqlc.narg('before_like_count')::int, sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid)
+)
+ORDER BY like_count DESC, created_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: SelectTimelinePage :many
+-- Each followed account contributes at most page_size chirps, read backwards
+-- along the (user_id, created_at, id) index, so a page costs one short index
+-- scan per followed account however much they have posted.
+SELECT timeline.* FROM follows
+CROSS JOIN LATERAL (
+    SELECT * FROM chirps
+    WHERE chirps.user_id = follows.followee_id AND chirps.deleted_at IS NULL
+    AND (
+        sqlc.narg('before_created_at')::timestamp IS NULL
+        OR (chirps.created_at, chirps.id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid)
+    )
+    ORDER BY chirps.created_at DESC, chirps.id DESC
+    LIMIT sqlc.arg('page_size')
+) AS timeline
+WHERE follows.follower_id = sqlc.arg('follower_id')
+ORDER BY timeline.created_at DESC, timeline.id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: SelectChirpIncludingDeleted :one
+SELECT * FROM chirps
 WHERE id = $1;
 
--- name: DeleteChirp :exec
-DELETE FROM chirps
-WHERE id = $1;
\ No newline at end of file
+-- name: RestoreChirp :one
+UPDATE chirps
+SET deleted_at = NULL, deleted_by = NULL
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: SelectDeletedChirpsPage :many
+SELECT * FROM chirps
+WHERE deleted_at IS NOT NULL
+AND (
+    sqlc.narg('before_deleted_at')::timestamp IS NULL
+    OR (deleted_at, id) < (sqlc.narg('before_deleted_at')::timestamp, sqlc.narg('before_id')::uuid)
+)
+ORDER BY deleted_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: PurgeDeletedChirps :many
+-- Returns one row per purged chirp and attachment so the caller can remove
+-- the media files, which the cascade takes out of the media table. Chirps
+-- with open reports are kept until a moderator has dealt with them.
+WITH purged AS (
+    DELETE FROM chirps
+    WHERE deleted_at < sqlc.arg('deleted_before')
+    AND NOT EXISTS (SELECT 1 FROM reports WHERE reports.chirp_id = chirps.id AND reports.status = 'open')
+    RETURNING id
+)
+SELECT purged.id, media.file_key, media.thumbnail_key FROM purged
+LEFT JOIN media ON media.chirp_id = purged.id;
@@ -0,0 +1,28 @@
+-- name: FollowUser :exec
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (follower_id, followee_id) DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: SelectFollowersPage :many
+SELECT follower_id AS user_id, created_at FROM follows
+WHERE followee_id = sqlc.arg('user_id')
+AND (
+    sqlc.narg('before_created_at')::timestamp IS NULL
+    OR (created_at, follower_id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid)
+)
+ORDER BY created_at DESC, follower_id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: SelectFollowingPage :many
+SELECT followee_id AS user_id, created_at FROM follows
+WHERE follower_id = sqlc.arg('user_id')
+AND (
+    sqlc.narg('before_created_at')::timestamp IS NULL
+    OR (created_at, followee_id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid)
+)
+ORDER BY created_at DESC, followee_id DESC
+LIMIT sqlc.arg('page_size');
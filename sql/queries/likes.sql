@@ -0,0 +1,24 @@
+-- name: LikeChirp :exec
+WITH inserted AS (
+    INSERT INTO likes (user_id, chirp_id, created_at)
+    VALUES ($1, $2, NOW())
+    ON CONFLICT (user_id, chirp_id) DO NOTHING
+    RETURNING chirp_id
+)
+UPDATE chirps
+SET like_count = like_count + 1
+WHERE id IN (SELECT chirp_id FROM inserted);
+
+-- name: UnlikeChirp :exec
+WITH deleted AS (
+    DELETE FROM likes
+    WHERE user_id = $1 AND chirp_id = $2
+    RETURNING chirp_id
+)
+UPDATE chirps
+SET like_count = like_count - 1
+WHERE id IN (SELECT chirp_id FROM deleted);
+
+-- name: SelectLikedChirpIDs :many
+SELECT chirp_id FROM likes
+WHERE user_id = $1 AND chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]);
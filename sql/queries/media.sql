@@ -0,0 +1,34 @@
+-- name: CreateMedia :one
+INSERT INTO media (id, user_id, content_type, file_key, thumbnail_key, width, height, size_bytes, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+RETURNING *;
+
+-- name: CountAttachableMedia :one
+SELECT count(*) FROM media
+WHERE id = ANY(sqlc.arg('ids')::uuid[]) AND user_id = sqlc.arg('user_id') AND chirp_id IS NULL;
+
+-- name: AttachMedia :execrows
+-- position follows the order of ids, so attachments display as uploaded.
+UPDATE media
+SET chirp_id = sqlc.arg('chirp_id'), position = array_position(sqlc.arg('ids')::uuid[], id)
+WHERE id = ANY(sqlc.arg('ids')::uuid[]) AND user_id = sqlc.arg('user_id') AND chirp_id IS NULL;
+
+-- name: PurgeUnattachedMedia :many
+-- Deletes uploads that were never attached to a chirp and returns their keys
+-- so the caller can remove the files.
+DELETE FROM media
+WHERE chirp_id IS NULL AND created_at < sqlc.arg('created_before')
+RETURNING file_key, thumbnail_key;
+
+-- name: SelectChirpMedia :many
+SELECT * FROM media
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY chirp_id, position;
+
+-- name: SelectDeletedChirpMedia :one
+-- Returns a row only if key is a file of a chirp that has been deleted or
+-- hidden.
+SELECT media.id FROM media
+JOIN chirps ON chirps.id = media.chirp_id
+WHERE (media.file_key = sqlc.arg('key') OR media.thumbnail_key = sqlc.arg('key'))
+AND chirps.deleted_at IS NOT NULL;
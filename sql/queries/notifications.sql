@@ -0,0 +1,36 @@
+-- name: CreateMentionNotifications :exec
+-- Mentioning yourself does not notify you.
+INSERT INTO notifications (id, user_id, type, chirp_id, actor_id, created_at)
+SELECT gen_random_uuid(), recipients.user_id, 'mention', sqlc.arg('chirp_id')::uuid, sqlc.arg('actor_id')::uuid, NOW()
+FROM unnest(sqlc.arg('user_ids')::uuid[]) AS recipients(user_id)
+WHERE recipients.user_id <> sqlc.arg('actor_id')::uuid
+ON CONFLICT (user_id, chirp_id, type) DO NOTHING;
+
+-- name: CountUnreadNotifications :one
+SELECT count(*) FROM notifications
+JOIN chirps ON chirps.id = notifications.chirp_id
+WHERE notifications.user_id = $1 AND notifications.read_at IS NULL AND chirps.deleted_at IS NULL;
+
+-- name: SelectNotificationsPage :many
+-- Notifications about deleted chirps are hidden until the chirp is restored
+-- or purged, which removes them.
+SELECT notifications.* FROM notifications
+JOIN chirps ON chirps.id = notifications.chirp_id
+WHERE notifications.user_id = sqlc.arg('user_id') AND chirps.deleted_at IS NULL
+AND (NOT sqlc.arg('unread_only')::boolean OR notifications.read_at IS NULL)
+AND (
+    sqlc.narg('before_created_at')::timestamp IS NULL
+    OR (notifications.created_at, notifications.id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid)
+)
+ORDER BY notifications.created_at DESC, notifications.id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: MarkNotificationRead :execrows
+UPDATE notifications
+SET read_at = COALESCE(read_at, NOW())
+WHERE id = $1 AND user_id = $2;
+
+-- name: MarkAllNotificationsRead :exec
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = $1 AND read_at IS NULL;
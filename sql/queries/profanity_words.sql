@@ -0,0 +1,3 @@
+-- name: ListProfanityWords :many
+SELECT * FROM profanity_words
+ORDER BY word;
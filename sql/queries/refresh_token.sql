@@ -1,27 +1,84 @@
 -- name: CheckRefreshToken :one
 SELECT * FROM refresh_tokens
-WHERE token = $1 AND expires_at > NOW() AND revoked_at IS NULL;
+WHERE token_hash = $1 AND expires_at > NOW() AND revoked_at IS NULL;
 
 -- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at)
+-- Starts a new token family, that is a new session.
+INSERT INTO refresh_tokens (token_hash, created_at, updated_at, user_id, expires_at, family_id, device_name, user_agent, ip_address, last_used_at)
 VALUES (
-    $1,
+    sqlc.arg('token_hash'),
     NOW(),
     NOW(),
-    $2,
-    $3
+    sqlc.arg('user_id'),
+    sqlc.arg('expires_at'),
+    gen_random_uuid(),
+    sqlc.arg('device_name'),
+    sqlc.arg('user_agent'),
+    sqlc.arg('ip_address'),
+    NOW()
 )
 RETURNING *;
 
 -- name: RevokeToken :one
+-- Returns no row if the token was already revoked.
 UPDATE refresh_tokens SET revoked_at = NOW(),
 updated_at = NOW()
-WHERE token = $1
+WHERE token_hash = $1 AND revoked_at IS NULL
 RETURNING *;
 
+-- name: RevokeTokenFamily :execrows
+UPDATE refresh_tokens SET revoked_at = NOW(),
+updated_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+-- name: RotateRefreshToken :one
+-- Revokes a live token and issues new_token_hash in its place, in the same
+-- family and session, in one statement. Only one of two concurrent rotations
+-- of the same token gets a row; the other sees it already revoked.
+WITH old AS (
+    UPDATE refresh_tokens
+    SET revoked_at = NOW(), updated_at = NOW(), replaced_by = sqlc.arg('new_token_hash')
+    WHERE refresh_tokens.token_hash = sqlc.arg('token_hash')
+    AND refresh_tokens.revoked_at IS NULL
+    AND refresh_tokens.expires_at > NOW()
+    RETURNING refresh_tokens.user_id, refresh_tokens.family_id, refresh_tokens.device_name
+)
+INSERT INTO refresh_tokens (token_hash, created_at, updated_at, user_id, expires_at, family_id, device_name, user_agent, ip_address, last_used_at)
+SELECT sqlc.arg('new_token_hash'), NOW(), NOW(), old.user_id, sqlc.arg('expires_at'), old.family_id, old.device_name, sqlc.arg('user_agent'), sqlc.arg('ip_address'), NOW()
+FROM old
+RETURNING *;
+
+-- name: SelectRefreshToken :one
+-- Returns the token whatever its state.
+SELECT * FROM refresh_tokens
+WHERE token_hash = $1;
+
 -- name: GetUserFromRefreshToken :one
 SELECT users.* FROM users
 JOIN refresh_tokens ON users.id = refresh_tokens.user_id
-WHERE refresh_tokens.token = $1
+WHERE refresh_tokens.token_hash = $1
 AND revoked_at IS NULL
-AND expires_at > NOW();
\ No newline at end of file
+AND expires_at > NOW();
+
+
+-- name: SelectSessions :many
+-- Lists a user's live token families, most recently used first. started_at
+-- is when the family's first token was issued.
+SELECT refresh_tokens.family_id, refresh_tokens.device_name, refresh_tokens.user_agent, refresh_tokens.ip_address,
+    refresh_tokens.last_used_at, refresh_tokens.expires_at,
+    (SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = refresh_tokens.family_id)::timestamp AS started_at
+FROM refresh_tokens
+WHERE refresh_tokens.user_id = $1
+AND refresh_tokens.revoked_at IS NULL
+AND refresh_tokens.expires_at > NOW()
+ORDER BY refresh_tokens.last_used_at DESC, refresh_tokens.family_id;
+
+-- name: RevokeSession :execrows
+UPDATE refresh_tokens SET revoked_at = NOW(),
+updated_at = NOW()
+WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL AND expires_at > NOW();
+
+-- name: RevokeUserTokens :execrows
+UPDATE refresh_tokens SET revoked_at = NOW(),
+updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
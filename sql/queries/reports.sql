@@ -0,0 +1,82 @@
+-- name: CreateReport :one
+-- Returns no row if the reporter already has an open report on the target.
+-- A chirp's body is copied into the report so it survives the chirp.
+INSERT INTO reports (id, reporter_id, chirp_id, reported_user_id, reason, details, created_at, updated_at, chirp_body)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, NOW(), NOW(), (SELECT chirps.body FROM chirps WHERE chirps.id = $2))
+ON CONFLICT DO NOTHING
+RETURNING *;
+
+-- name: SelectReport :one
+SELECT * FROM reports
+WHERE id = $1;
+
+-- name: SelectReportsPage :many
+-- The queue is worked oldest first.
+SELECT * FROM reports
+WHERE (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status')::text)
+AND (sqlc.narg('reason')::text IS NULL OR reason = sqlc.narg('reason')::text)
+AND (sqlc.narg('chirp_id')::uuid IS NULL OR chirp_id = sqlc.narg('chirp_id')::uuid)
+AND (sqlc.narg('reported_user_id')::uuid IS NULL OR reported_user_id = sqlc.narg('reported_user_id')::uuid)
+AND (
+    sqlc.narg('after_created_at')::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid)
+)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('page_size');
+
+-- name: ApplyReportAction :one
+-- Moves the report to status if it is in one of from_statuses, carries out
+-- the action and records it, all in one statement. Returns no row if the
+-- report is not in one of from_statuses, or if the action is a suspension
+-- and the reported user's role is not one of suspendable_roles. The user's
+-- row is locked while that is checked, so a concurrent role change cannot
+-- slip past it. A hidden chirp is marked deleted by the moderator so its
+-- author cannot restore it; a suspension only ever extends an existing one,
+-- never lifts a ban, and revokes the user's refresh tokens.
+WITH target AS (
+    SELECT users.id FROM users
+    WHERE users.id = (SELECT reports.reported_user_id FROM reports WHERE reports.id = sqlc.arg('id'))
+    AND (sqlc.narg('suspend_until')::timestamp IS NULL OR users.role = ANY(sqlc.arg('suspendable_roles')::text[]))
+    FOR UPDATE
+), report AS (
+    UPDATE reports
+    SET status = sqlc.arg('status')::text,
+        updated_at = NOW(),
+        resolved_at = CASE WHEN sqlc.arg('status')::text = 'open' THEN NULL ELSE NOW() END,
+        resolved_by = CASE WHEN sqlc.arg('status')::text = 'open' THEN NULL ELSE sqlc.arg('moderator_id')::uuid END
+    WHERE reports.id = sqlc.arg('id') AND reports.status = ANY(sqlc.arg('from_statuses')::text[])
+    AND reports.reported_user_id = (SELECT target.id FROM target)
+    RETURNING *
+), hidden AS (
+    UPDATE chirps
+    SET deleted_at = COALESCE(chirps.deleted_at, NOW()), deleted_by = sqlc.arg('moderator_id')::uuid
+    WHERE sqlc.arg('hide_chirp')::boolean AND chirps.id = (SELECT report.chirp_id FROM report)
+), suspended AS (
+    UPDATE users
+    SET status = CASE WHEN users.status = 'banned' THEN 'banned' ELSE 'suspended' END,
+        suspended_until = GREATEST(users.suspended_until, sqlc.narg('suspend_until')::timestamp),
+        updated_at = NOW()
+    WHERE sqlc.narg('suspend_until')::timestamp IS NOT NULL AND users.id = (SELECT report.reported_user_id FROM report)
+), revoked AS (
+    UPDATE refresh_tokens
+    SET revoked_at = NOW(), updated_at = NOW()
+    WHERE sqlc.narg('suspend_until')::timestamp IS NOT NULL
+    AND refresh_tokens.user_id = (SELECT report.reported_user_id FROM report)
+    AND refresh_tokens.revoked_at IS NULL
+), logged AS (
+    INSERT INTO moderation_actions (id, report_id, moderator_id, action, chirp_id, user_id, note, created_at)
+    SELECT gen_random_uuid(), report.id, sqlc.arg('moderator_id')::uuid, sqlc.arg('action')::text, report.chirp_id, report.reported_user_id, sqlc.arg('note')::text, NOW()
+    FROM report
+)
+SELECT * FROM report;
+
+-- name: SelectModerationActionsPage :many
+SELECT * FROM moderation_actions
+WHERE (sqlc.narg('report_id')::uuid IS NULL OR report_id = sqlc.narg('report_id')::uuid)
+AND (sqlc.narg('moderator_id')::uuid IS NULL OR moderator_id = sqlc.narg('moderator_id')::uuid)
+AND (
+    sqlc.narg('before_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
@@ -27,3 +27,45 @@ UPDATE users
 SET is_chirpy_red = true
 WHERE id = $1
 RETURNING *;
+
+-- name: SelectUserID :one
+SELECT id FROM users
+WHERE id = $1;
+
+-- name: SelectUserRole :one
+SELECT role FROM users
+WHERE id = $1;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserRoleByEmail :execrows
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE email = $1;
+
+-- name: SelectAccountRestriction :one
+-- Returns a row only if the user is banned or currently suspended.
+SELECT status, suspended_until FROM users
+WHERE id = $1
+AND (status = 'banned' OR (status = 'suspended' AND suspended_until > NOW()));
+
+-- name: SetUserStatus :one
+-- Sets the account status and, unless the user is being made active again,
+-- revokes all of their refresh tokens in the same statement.
+WITH updated AS (
+    UPDATE users
+    SET status = sqlc.arg('status')::text, suspended_until = sqlc.narg('suspended_until')::timestamp, updated_at = NOW()
+    WHERE users.id = sqlc.arg('id')
+    RETURNING users.id, users.email, users.status, users.suspended_until
+), revoked AS (
+    UPDATE refresh_tokens
+    SET revoked_at = NOW(), updated_at = NOW()
+    WHERE sqlc.arg('status')::text <> 'active'
+    AND refresh_tokens.user_id = (SELECT updated.id FROM updated)
+    AND refresh_tokens.revoked_at IS NULL
+)
+SELECT id, email, status, suspended_until FROM updated;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE profanity_words (
+    word TEXT PRIMARY KEY,
+    strategy TEXT NOT NULL DEFAULT 'mask'
+        CHECK (strategy IN ('mask', 'preserve', 'reject'))
+);
+
+INSERT INTO profanity_words (word) VALUES
+    ('kerfuffle'),
+    ('sharbert'),
+    ('fornax');
+
+-- +goose Down
+DROP TABLE profanity_words;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- Deleting a chirp detaches its replies instead of deleting them: replies
+-- belong to their own authors and become the root of a new thread.
+ALTER TABLE chirps
+ADD COLUMN in_reply_to UUID NULL REFERENCES chirps(id) ON DELETE SET NULL;
+
+CREATE INDEX chirps_in_reply_to_idx ON chirps (in_reply_to, created_at, id);
+
+-- +goose Down
+DROP INDEX chirps_in_reply_to_idx;
+ALTER TABLE chirps
+DROP COLUMN in_reply_to;
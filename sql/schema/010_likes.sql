@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE likes (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE (user_id, chirp_id)
+);
+
+CREATE INDEX likes_chirp_id_idx ON likes (chirp_id);
+
+-- like_count is kept in step with likes by LikeChirp/UnlikeChirp so that
+-- listings can sort and paginate by it without aggregating.
+ALTER TABLE chirps
+ADD COLUMN like_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX chirps_like_count_idx ON chirps (like_count, created_at, id);
+
+-- +goose Down
+DROP INDEX chirps_like_count_idx;
+ALTER TABLE chirps
+DROP COLUMN like_count;
+DROP TABLE likes;
@@ -0,0 +1,29 @@
+-- +goose Up
+-- created_at is copied from the chirp so that tag listings and trending
+-- counts can be served from chirp_hashtags alone.
+CREATE TABLE chirp_hashtags (
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chirp_id, tag)
+);
+
+CREATE INDEX chirp_hashtags_tag_idx ON chirp_hashtags (tag, created_at, chirp_id);
+CREATE INDEX chirp_hashtags_created_at_idx ON chirp_hashtags (created_at);
+
+CREATE TABLE chirp_mentions (
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    handle TEXT NOT NULL,
+    PRIMARY KEY (chirp_id, handle)
+);
+
+CREATE INDEX chirp_mentions_user_id_idx ON chirp_mentions (user_id);
+
+-- A user's handle is the local part of their email address.
+CREATE INDEX users_handle_idx ON users (lower(split_part(email, '@', 1)));
+
+-- +goose Down
+DROP INDEX users_handle_idx;
+DROP TABLE chirp_mentions;
+DROP TABLE chirp_hashtags;
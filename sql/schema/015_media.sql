@@ -0,0 +1,21 @@
+-- +goose Up
+-- Media is uploaded on its own and attached to a chirp when the chirp is
+-- created; chirp_id stays NULL until then.
+CREATE TABLE media (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    chirp_id UUID REFERENCES chirps(id) ON DELETE CASCADE,
+    position INTEGER,
+    content_type TEXT NOT NULL,
+    file_key TEXT NOT NULL,
+    thumbnail_key TEXT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    size_bytes INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX media_chirp_id_idx ON media (chirp_id, position);
+
+-- +goose Down
+DROP TABLE media;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- Deleting a chirp only marks it. deleted_by tells an owner's delete, which
+-- the owner may undo, from one made by someone else. Deleted chirps are
+-- purged for good once the retention period has passed.
+ALTER TABLE chirps
+ADD COLUMN deleted_at TIMESTAMP NULL,
+ADD COLUMN deleted_by UUID NULL REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX chirps_deleted_at_idx ON chirps (deleted_at, id) WHERE deleted_at IS NOT NULL;
+
+-- +goose Down
+DELETE FROM chirps WHERE deleted_at IS NOT NULL;
+DROP INDEX chirps_deleted_at_idx;
+ALTER TABLE chirps
+DROP COLUMN deleted_by,
+DROP COLUMN deleted_at;
@@ -0,0 +1,46 @@
+-- +goose Up
+CREATE TABLE reports (
+    id UUID PRIMARY KEY,
+    reporter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- chirp_id is NULL for a report about a user rather than a chirp.
+    chirp_id UUID REFERENCES chirps(id) ON DELETE CASCADE,
+    reported_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'sexual_content', 'misinformation', 'other')),
+    details TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'actioned', 'dismissed')),
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP,
+    resolved_by UUID REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- One open report per reporter and target. User reports have no chirp, so
+-- the user stands in for it.
+CREATE UNIQUE INDEX reports_open_target_idx ON reports (reporter_id, reported_user_id, COALESCE(chirp_id, reported_user_id))
+WHERE status = 'open';
+CREATE INDEX reports_status_created_at_idx ON reports (status, created_at, id);
+
+-- moderation_actions is the audit trail. It keeps no foreign keys to the
+-- chirp or user acted on so entries outlive them.
+CREATE TABLE moderation_actions (
+    id UUID PRIMARY KEY,
+    report_id UUID REFERENCES reports(id) ON DELETE SET NULL,
+    moderator_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    action TEXT NOT NULL,
+    chirp_id UUID,
+    user_id UUID,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX moderation_actions_created_at_idx ON moderation_actions (created_at, id);
+CREATE INDEX moderation_actions_report_id_idx ON moderation_actions (report_id);
+
+ALTER TABLE users
+ADD COLUMN suspended_until TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN suspended_until;
+DROP TABLE moderation_actions;
+DROP TABLE reports;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- A suspended user is active again once suspended_until has passed; a ban
+-- has no end.
+ALTER TABLE users
+ADD COLUMN status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'suspended', 'banned'));
+
+UPDATE users SET status = 'suspended' WHERE suspended_until IS NOT NULL;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN status;
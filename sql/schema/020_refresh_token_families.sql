@@ -0,0 +1,21 @@
+-- +goose Up
+-- Every login starts a family of refresh tokens; each refresh revokes the
+-- token it was given and issues the next one in the same family.
+-- replaced_by is set on a token that was rotated, so presenting it again
+-- can be told apart from presenting one that was simply revoked.
+ALTER TABLE refresh_tokens
+ADD COLUMN family_id UUID,
+ADD COLUMN replaced_by TEXT;
+
+UPDATE refresh_tokens SET family_id = gen_random_uuid();
+
+ALTER TABLE refresh_tokens
+ALTER COLUMN family_id SET NOT NULL;
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+
+-- +goose Down
+DROP INDEX refresh_tokens_family_id_idx;
+ALTER TABLE refresh_tokens
+DROP COLUMN replaced_by,
+DROP COLUMN family_id;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- Refresh tokens are stored as the hex SHA-256 of the token the client
+-- holds. Existing rows are hashed in place so nobody is logged out.
+ALTER TABLE refresh_tokens
+RENAME COLUMN token TO token_hash;
+
+UPDATE refresh_tokens
+SET token_hash = encode(sha256(convert_to(token_hash, 'UTF8')), 'hex'),
+    replaced_by = encode(sha256(convert_to(replaced_by, 'UTF8')), 'hex');
+
+-- +goose Down
+-- Hashes cannot be turned back into tokens, so every session ends.
+DELETE FROM refresh_tokens;
+
+ALTER TABLE refresh_tokens
+RENAME COLUMN token_hash TO token;
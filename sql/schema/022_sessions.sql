@@ -0,0 +1,25 @@
+-- +goose Up
+-- A session is a token family. The live token in a family carries what the
+-- client last looked like; device_name is chosen at login and kept across
+-- rotations.
+ALTER TABLE refresh_tokens
+ADD COLUMN device_name TEXT NOT NULL DEFAULT '',
+ADD COLUMN user_agent TEXT NOT NULL DEFAULT '',
+ADD COLUMN ip_address TEXT NOT NULL DEFAULT '',
+ADD COLUMN last_used_at TIMESTAMP;
+
+UPDATE refresh_tokens SET last_used_at = created_at;
+
+ALTER TABLE refresh_tokens
+ALTER COLUMN last_used_at SET NOT NULL;
+
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id)
+WHERE revoked_at IS NULL;
+
+-- +goose Down
+DROP INDEX refresh_tokens_user_id_idx;
+ALTER TABLE refresh_tokens
+DROP COLUMN last_used_at,
+DROP COLUMN ip_address,
+DROP COLUMN user_agent,
+DROP COLUMN device_name;
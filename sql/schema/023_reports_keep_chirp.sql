@@ -0,0 +1,23 @@
+-- +goose Up
+-- Reports outlive the chirps they are about: purging a chirp only unlinks
+-- its reports, which keep a copy of the body as it was reported.
+ALTER TABLE reports
+ADD COLUMN chirp_body TEXT;
+
+UPDATE reports SET chirp_body = chirps.body
+FROM chirps
+WHERE chirps.id = reports.chirp_id;
+
+ALTER TABLE reports
+DROP CONSTRAINT reports_chirp_id_fkey,
+ADD CONSTRAINT reports_chirp_id_fkey FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE SET NULL;
+
+-- +goose Down
+DELETE FROM reports WHERE chirp_id IS NULL AND chirp_body IS NOT NULL;
+
+ALTER TABLE reports
+DROP CONSTRAINT reports_chirp_id_fkey,
+ADD CONSTRAINT reports_chirp_id_fkey FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE;
+
+ALTER TABLE reports
+DROP COLUMN chirp_body;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Serving a file looks its row up by key to check the chirp is not deleted.
+CREATE INDEX media_file_key_idx ON media (file_key);
+CREATE INDEX media_thumbnail_key_idx ON media (thumbnail_key);
+
+-- +goose Down
+DROP INDEX media_thumbnail_key_idx;
+DROP INDEX media_file_key_idx;
@@ -0,0 +1,42 @@
+-- +goose Up
+-- The search document becomes a generated column on chirps, so Postgres keeps
+-- it in step with the body without a side table or trigger.
+ALTER TABLE chirps
+ADD COLUMN search_document TSVECTOR GENERATED ALWAYS AS (to_tsvector('english', body)) STORED;
+
+CREATE INDEX chirps_search_document_idx ON chirps USING GIN (search_document);
+
+DROP TRIGGER chirps_search_refresh ON chirps;
+DROP FUNCTION chirp_search_refresh();
+DROP TABLE chirp_search;
+
+-- +goose Down
+CREATE TABLE chirp_search (
+    chirp_id UUID PRIMARY KEY REFERENCES chirps(id) ON DELETE CASCADE,
+    document TSVECTOR NOT NULL
+);
+
+CREATE INDEX chirp_search_document_idx ON chirp_search USING GIN (document);
+
+-- +goose StatementBegin
+CREATE FUNCTION chirp_search_refresh() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO chirp_search (chirp_id, document)
+    VALUES (NEW.id, to_tsvector('english', NEW.body))
+    ON CONFLICT (chirp_id) DO UPDATE SET document = EXCLUDED.document;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER chirps_search_refresh
+AFTER INSERT OR UPDATE OF body ON chirps
+FOR EACH ROW EXECUTE FUNCTION chirp_search_refresh();
+
+INSERT INTO chirp_search (chirp_id, document)
+SELECT id, search_document FROM chirps;
+
+DROP INDEX chirps_search_document_idx;
+
+ALTER TABLE chirps
+DROP COLUMN search_document;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- Search matches against an expression index instead of a stored column, so
+-- the document never leaves the server: chirp queries do not read it back and
+-- search computes it only for the chirps it returns.
+DROP INDEX chirps_search_document_idx;
+
+ALTER TABLE chirps
+DROP COLUMN search_document;
+
+CREATE INDEX chirps_body_search_idx ON chirps USING GIN (to_tsvector('english', body));
+
+-- +goose Down
+DROP INDEX chirps_body_search_idx;
+
+ALTER TABLE chirps
+ADD COLUMN search_document TSVECTOR GENERATED ALWAYS AS (to_tsvector('english', body)) STORED;
+
+CREATE INDEX chirps_search_document_idx ON chirps USING GIN (search_document);
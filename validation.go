@@ -2,18 +2,53 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
+	"unicode/utf8"
+
+	"github.com/Lockenrocky/chirpy/internal/profanity"
 )
 
-func handlerValidation(w http.ResponseWriter, r *http.Request) {
-	type parameters struct {
-		Body string `json:"body"`
+const maxChirpLength = 140
+
+var (
+	errChirpEmpty      = errors.New("Chirp is empty")
+	errChirpTooLong    = errors.New("Chirp is too long")
+	errChirpProhibited = errors.New("Chirp contains a prohibited word")
+)
+
+var chirpErrorCodes = map[error]string{
+	errChirpEmpty:      errCodeChirpEmpty,
+	errChirpTooLong:    errCodeChirpTooLong,
+	errChirpProhibited: errCodeChirpProhibited,
+}
+
+// validateChirp is the single set of rules every chirp body goes through
+// before it is accepted. It returns the cleaned body that should be stored.
+func (cfg *apiConfig) validateChirp(body string) (string, error) {
+	if strings.TrimSpace(body) == "" {
+		return "", errChirpEmpty
+	}
+	if utf8.RuneCountInString(body) > maxChirpLength {
+		return "", errChirpTooLong
 	}
 
-	type errorVals struct {
-		Error string `json:"error"`
+	cleaned, err := cfg.profanity.Clean(body)
+	if err != nil {
+		var rejected *profanity.RejectedError
+		if errors.As(err, &rejected) {
+			return "", errChirpProhibited
+		}
+		return "", err
+	}
+	return cleaned, nil
+}
+
+func (cfg *apiConfig) handlerValidation(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
 	}
 
 	type validVals struct {
@@ -23,75 +58,29 @@ func handlerValidation(w http.ResponseWriter, r *http.Request) {
 	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
 	err := decoder.Decode(&params)
-
-	// Error with JSON
 	if err != nil {
-		errBody := errorVals{
-			Error: "Something went wrong",
-		}
-		dat, err := json.Marshal(errBody)
-		if err != nil {
-			log.Printf("Error marshalling JSON %s", err)
-			w.WriteHeader(500)
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(500)
-		w.Write(dat)
+		respondWithError(w, http.StatusBadRequest, errCodeInvalidRequest, "Could not decode parameters")
 		return
 	}
 
-	// Chirp is too long
-	if len(params.Body) > 140 {
-		errBody := errorVals{
-			Error: "Chirp is too long",
-		}
-		dat, err := json.Marshal(errBody)
-		if err != nil {
-			log.Printf("Error marshalling JSON %s", err)
-			w.WriteHeader(500)
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(400)
-		w.Write(dat)
+	cleaned_body, err := cfg.validateChirp(params.Body)
+	if err != nil {
+		respondWithChirpError(w, err)
 		return
 	}
 
-	// Valid chirp
-
-	// check for bad words
-	badWords := map[string]struct{}{
-		"kerfuffle": {},
-		"sharbert":  {},
-		"fornax":    {},
-	}
-
-	cleaned_body := checkProfanity(params.Body, badWords)
-
-	validBody := validVals{
+	respondWithJSON(w, http.StatusOK, validVals{
 		Cleaned_body: cleaned_body,
-	}
-	dat, err := json.Marshal(validBody)
-	if err != nil {
-		log.Printf("Error marshalling JSON %s", err)
-		w.WriteHeader(500)
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(dat)
-
+	})
 }
 
-func checkProfanity(payload string, badWords map[string]struct{}) string {
-	words := strings.Split(payload, " ")
-	for i, word := range words {
-		lower := strings.ToLower(word)
-		if _, ok := badWords[lower]; ok {
-			words[i] = "****"
-		}
+// respondWithChirpError reports an error returned by validateChirp.
+func respondWithChirpError(w http.ResponseWriter, err error) {
+	code, ok := chirpErrorCodes[err]
+	if !ok {
+		log.Printf("Error validating chirp: %s", err)
+		respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Could not validate chirp")
+		return
 	}
-	cleaned_payload := strings.Join(words, " ")
-	return cleaned_payload
+	respondWithError(w, http.StatusBadRequest, code, err.Error())
 }
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Lockenrocky/chirpy/internal/profanity"
+)
+
+func newTestProfanityFilter(t *testing.T) *profanity.Filter {
+	t.Helper()
+	f, err := profanity.New(context.Background(), profanity.Static(
+		profanity.Rule{Word: "kerfuffle", Strategy: profanity.StrategyMask},
+		profanity.Rule{Word: "sharbert", Strategy: profanity.StrategyMask},
+		profanity.Rule{Word: "fornax", Strategy: profanity.StrategyReject},
+	))
+	if err != nil {
+		t.Fatalf("profanity.New() error = %v", err)
+	}
+	return f
+}
+
+func TestValidateChirp(t *testing.T) {
+	cfg := &apiConfig{profanity: newTestProfanityFilter(t)}
+
+	tests := []struct {
+		name    string
+		body    string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "Clean chirp",
+			body: "I had something interesting for breakfast",
+			want: "I had something interesting for breakfast",
+		},
+		{
+			name: "Profanity is masked",
+			body: "I hear Mastodon is better than Chirpy. sharbert I need to migrate",
+			want: "I hear Mastodon is better than Chirpy. **** I need to migrate",
+		},
+		{
+			name: "Profanity next to punctuation",
+			body: "What a Kerfuffle!",
+			want: "What a ****!",
+		},
+		{
+			name:    "Rejected word",
+			body:    "fornax, again",
+			wantErr: errChirpProhibited,
+		},
+		{
+			name:    "Too long",
+			body:    strings.Repeat("a", maxChirpLength+1),
+			wantErr: errChirpTooLong,
+		},
+		{
+			name: "Exactly at limit in runes",
+			body: strings.Repeat("é", maxChirpLength),
+			want: strings.Repeat("é", maxChirpLength),
+		},
+		{
+			name:    "Empty",
+			body:    "   ",
+			wantErr: errChirpEmpty,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cfg.validateChirp(tt.body)
+			if err != tt.wantErr {
+				t.Fatalf("validateChirp() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("validateChirp() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerValidation(t *testing.T) {
+	cfg := &apiConfig{profanity: newTestProfanityFilter(t)}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/validate_chirp", strings.NewReader(`{"body":"What a kerfuffle!"}`))
+	rec := httptest.NewRecorder()
+	cfg.handlerValidation(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var body struct {
+		CleanedBody string `json:"cleaned_body"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("body is not JSON: %v", err)
+	}
+	if body.CleanedBody != "What a ****!" {
+		t.Errorf("cleaned_body = %q, want %q", body.CleanedBody, "What a ****!")
+	}
+}